@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"bytes"
 	"drill/models"
 	"encoding/json"
 	"os"
@@ -11,6 +12,7 @@ import (
 
 const (
 	MaxCachedRequests = 5
+	MaxSnapshots      = 2 // earlier fetches kept per request for comparison
 	CacheFileName     = ".drill_cache.json"
 )
 
@@ -20,6 +22,110 @@ type CachedRequest struct {
 	Events      []models.Event   `json:"events"`
 	Commands    []models.Command `json:"commands"`
 	IsMock      bool             `json:"isMock"`
+	Environment string           `json:"environment,omitempty"`
+	Lookup      string           `json:"lookup,omitempty"`   // kind of ID, empty for aggregate
+	Previous    []Snapshot       `json:"previous,omitempty"` // earlier fetches, newest first
+}
+
+// Snapshot is the data from an earlier fetch of the same request
+type Snapshot struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Events    []models.Event   `json:"events"`
+	Commands  []models.Command `json:"commands"`
+}
+
+// cachedEvent and cachedCommand keep the service name, which models leave
+// out of their JSON, so that cached histories still say where rows came from
+type cachedEvent struct {
+	models.Event
+	Service string `json:"service,omitempty"`
+}
+
+type cachedCommand struct {
+	models.Command
+	Service string `json:"service,omitempty"`
+}
+
+func toCachedEvents(events []models.Event) []cachedEvent {
+	out := make([]cachedEvent, len(events))
+	for i, e := range events {
+		out[i] = cachedEvent{Event: e, Service: e.ServiceName}
+	}
+	return out
+}
+
+func fromCachedEvents(cached []cachedEvent) []models.Event {
+	out := make([]models.Event, len(cached))
+	for i, c := range cached {
+		out[i] = c.Event
+		out[i].ServiceName = c.Service
+	}
+	return out
+}
+
+func toCachedCommands(commands []models.Command) []cachedCommand {
+	out := make([]cachedCommand, len(commands))
+	for i, c := range commands {
+		out[i] = cachedCommand{Command: c, Service: c.ServiceName}
+	}
+	return out
+}
+
+func fromCachedCommands(cached []cachedCommand) []models.Command {
+	out := make([]models.Command, len(cached))
+	for i, c := range cached {
+		out[i] = c.Command
+		out[i].ServiceName = c.Service
+	}
+	return out
+}
+
+func (r CachedRequest) MarshalJSON() ([]byte, error) {
+	type plain CachedRequest
+	return json.Marshal(struct {
+		plain
+		Events   []cachedEvent   `json:"events"`
+		Commands []cachedCommand `json:"commands"`
+	}{plain(r), toCachedEvents(r.Events), toCachedCommands(r.Commands)})
+}
+
+func (r *CachedRequest) UnmarshalJSON(data []byte) error {
+	type plain CachedRequest
+	var v struct {
+		plain
+		Events   []cachedEvent   `json:"events"`
+		Commands []cachedCommand `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = CachedRequest(v.plain)
+	r.Events, r.Commands = fromCachedEvents(v.Events), fromCachedCommands(v.Commands)
+	return nil
+}
+
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	type plain Snapshot
+	return json.Marshal(struct {
+		plain
+		Events   []cachedEvent   `json:"events"`
+		Commands []cachedCommand `json:"commands"`
+	}{plain(s), toCachedEvents(s.Events), toCachedCommands(s.Commands)})
+}
+
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	type plain Snapshot
+	var v struct {
+		plain
+		Events   []cachedEvent   `json:"events"`
+		Commands []cachedCommand `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Snapshot(v.plain)
+	s.Events, s.Commands = fromCachedEvents(v.Events), fromCachedCommands(v.Commands)
+	return nil
 }
 
 type Cache struct {
@@ -70,12 +176,23 @@ func (c *Cache) Save() error {
 	return os.WriteFile(cachePath, data, 0644)
 }
 
-func (c *Cache) AddRequest(aggregateID string, events []models.Event, commands []models.Command, isMock bool) {
-	// Remove existing request with same aggregate ID
+func (c *Cache) AddRequest(aggregateID string, events []models.Event, commands []models.Command, isMock bool, environment, lookup string) {
+	// Remove existing request with same ID and kind in the same environment,
+	// keeping its data as a snapshot unless nothing changed
+	var previous []Snapshot
 	filtered := make([]CachedRequest, 0)
 	for _, r := range c.Requests {
-		if r.AggregateID != aggregateID {
+		if r.AggregateID != aggregateID || r.Environment != environment || r.Lookup != lookup {
 			filtered = append(filtered, r)
+			continue
+		}
+		if sameData(toCachedEvents(r.Events), toCachedEvents(events)) && sameData(toCachedCommands(r.Commands), toCachedCommands(commands)) {
+			previous = r.Previous
+			continue
+		}
+		previous = append([]Snapshot{{Timestamp: r.Timestamp, Events: r.Events, Commands: r.Commands}}, r.Previous...)
+		if len(previous) > MaxSnapshots {
+			previous = previous[:MaxSnapshots]
 		}
 	}
 	c.Requests = filtered
@@ -87,6 +204,9 @@ func (c *Cache) AddRequest(aggregateID string, events []models.Event, commands [
 		Events:      events,
 		Commands:    commands,
 		IsMock:      isMock,
+		Environment: environment,
+		Lookup:      lookup,
+		Previous:    previous,
 	}
 
 	c.Requests = append([]CachedRequest{newRequest}, c.Requests...)
@@ -97,6 +217,16 @@ func (c *Cache) AddRequest(aggregateID string, events []models.Event, commands [
 	}
 }
 
+// sameData reports whether two event or command lists serialise the same
+func sameData(a, b interface{}) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	return err == nil && bytes.Equal(x, y)
+}
+
 func (c *Cache) GetRequest(aggregateID string) *CachedRequest {
 	for i := range c.Requests {
 		if c.Requests[i].AggregateID == aggregateID {
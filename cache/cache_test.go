@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"drill/models"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestServiceNamesSurviveRoundTrip(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []models.Event{{Metadata: models.EventMetadata{EventID: "e1", PersistedAt: at}, ServiceName: "orders"}}
+	commands := []models.Command{{CommandID: "c1", PersistedAt: at, ServiceName: "billing"}}
+
+	c := &Cache{}
+	c.AddRequest("agg", events, commands, false, "", "")
+	c.AddRequest("agg", append(events, models.Event{ServiceName: "shipping"}), commands, false, "", "")
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Cache
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	req := loaded.GetRequest("agg")
+	if req == nil || len(req.Previous) != 1 {
+		t.Fatalf("request = %+v, want one with a snapshot", req)
+	}
+	if got := req.Events[1].ServiceName; got != "shipping" {
+		t.Errorf("event service = %q", got)
+	}
+	if got := req.Commands[0].ServiceName; got != "billing" {
+		t.Errorf("command service = %q", got)
+	}
+	if got := req.Previous[0].Events[0].ServiceName; got != "orders" {
+		t.Errorf("snapshot event service = %q", got)
+	}
+	if got := req.Previous[0].Commands[0].ServiceName; got != "billing" {
+		t.Errorf("snapshot command service = %q", got)
+	}
+}
+
+func TestAddRequestSkipsUnchangedData(t *testing.T) {
+	events := []models.Event{{Metadata: models.EventMetadata{EventID: "e1"}, ServiceName: "orders"}}
+	c := &Cache{}
+	c.AddRequest("agg", events, nil, false, "", "")
+	c.AddRequest("agg", events, nil, false, "", "")
+	if n := len(c.Requests[0].Previous); n != 0 {
+		t.Fatalf("%d snapshots of unchanged data", n)
+	}
+
+	// The same rows from another service are a change
+	moved := []models.Event{{Metadata: models.EventMetadata{EventID: "e1"}, ServiceName: "billing"}}
+	c.AddRequest("agg", moved, nil, false, "", "")
+	if n := len(c.Requests[0].Previous); n != 1 {
+		t.Fatalf("%d snapshots, want 1", n)
+	}
+}
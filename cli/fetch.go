@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"context"
+	"drill/config"
+	"drill/fetcher"
+	"drill/filter"
+	"drill/models"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Record is one command or event in headless output
+type Record struct {
+	Type          string               `json:"type"` // "command" or "event"
+	ID            string               `json:"id"`
+	Alias         string               `json:"alias"`
+	Status        models.CommandStatus `json:"status,omitempty"`
+	Service       string               `json:"service"`
+	PersistedAt   time.Time            `json:"persistedAt"`
+	CorrelationID string               `json:"correlationId"`
+	AggregateID   string               `json:"aggregateId"`
+	Payload       string               `json:"payload"`
+
+	PayloadDecoding string `json:"payloadDecoding,omitempty"` // how the payload was decoded from its stored form
+	PayloadError    string `json:"payloadError,omitempty"`    // why the stored payload could not be decoded
+}
+
+// BuildRecords merges commands and events into a single list ordered by
+// persistedAt, with commands ahead of events persisted at the same instant.
+// Payloads are written as stored unless decoded is set.
+func BuildRecords(events []models.Event, commands []models.Command, decoded bool) []Record {
+	records := make([]Record, 0, len(events)+len(commands))
+	for _, c := range commands {
+		if !decoded {
+			c = storedCommand(c)
+		}
+		records = append(records, Record{
+			Type:          "command",
+			ID:            c.CommandID,
+			Alias:         c.CommandAlias,
+			Status:        c.CommandStatus,
+			Service:       c.ServiceName,
+			PersistedAt:   c.PersistedAt,
+			CorrelationID: c.CorrelationID,
+			AggregateID:   c.AggregateID,
+			Payload:       c.Payload,
+
+			PayloadDecoding: c.PayloadDecoding,
+			PayloadError:    c.PayloadError,
+		})
+	}
+	for _, e := range events {
+		if !decoded {
+			e = storedEvent(e)
+		}
+		records = append(records, Record{
+			Type:          "event",
+			ID:            e.Metadata.EventID,
+			Alias:         e.Metadata.EventAlias,
+			Service:       e.ServiceName,
+			PersistedAt:   e.Metadata.PersistedAt,
+			CorrelationID: e.Metadata.CorrelationID,
+			AggregateID:   e.Metadata.AggregateID,
+			Payload:       e.Payload,
+
+			PayloadDecoding: e.PayloadDecoding,
+			PayloadError:    e.PayloadError,
+		})
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].PersistedAt.Before(records[j].PersistedAt)
+	})
+
+	return records
+}
+
+// storedEvent returns e with its payload as stored and no decoding details
+func storedEvent(e models.Event) models.Event {
+	if e.RawPayload != "" {
+		e.Payload = e.RawPayload
+	}
+	e.RawPayload, e.PayloadDecoding, e.PayloadError = "", "", ""
+	return e
+}
+
+// storedCommand returns c with its payload as stored and no decoding details
+func storedCommand(c models.Command) models.Command {
+	if c.RawPayload != "" {
+		c.Payload = c.RawPayload
+	}
+	c.RawPayload, c.PayloadDecoding, c.PayloadError = "", "", ""
+	return c
+}
+
+// RunFetch implements `drill fetch <id> [--by kind] [--filter expr] [--format json|ndjson|table] [--decode]`
+// and returns the process exit code: 0 on success, 1 on errors, 2 on bad usage
+// and 3 when some service calls failed, after writing what the others
+// returned. Payloads are written as stored unless --decode is given; the
+// filter matches decoded payloads either way. The --env flag is handled by
+// main before cfg is passed in.
+func RunFetch(args []string, cfg *config.Config) int {
+	fs := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: json, ndjson or table")
+	by := fs.String("by", string(models.LookupAggregate), "kind of ID given: aggregate, correlation or command")
+	retries := fs.Int("retries", cfg.Retry.MaxRetries, "retries per call for transient failures")
+	filterText := fs.String("filter", "", "only output rows matching a filter expression, e.g. 'service=orders AND payload.amount>50'")
+	decode := fs.Bool("decode", false, "write payloads decoded by the service's payload decoders instead of as stored")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: drill fetch <id> [--by aggregate|correlation|command] [--env name] [--filter expr] [--format json|ndjson|table] [--decode] [--retries n]")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "Exits with status 3 when any service call failed; rows from the others are still written.")
+	}
+
+	// Allow flags both before and after the positional ID
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	id := strings.TrimSpace(fs.Arg(0))
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+
+	switch *format {
+	case "json", "ndjson", "table":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q, expected json, ndjson or table\n", *format)
+		return 2
+	}
+
+	kind := models.LookupKind(*by)
+	switch kind {
+	case models.LookupAggregate, models.LookupCorrelation, models.LookupCommand:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown lookup %q, expected aggregate, correlation or command\n", *by)
+		return 2
+	}
+
+	var match *filter.Filter
+	if *filterText != "" {
+		var err error
+		if match, err = filter.Parse(*filterText); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	if err := fetcher.CheckID(cfg.Services, kind, id); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if len(cfg.Services) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no services configured. Create a .drill.yaml file")
+		return 1
+	}
+
+	f := fetcher.NewFetcher(cfg.Services)
+	policy := cfg.Retry
+	policy.MaxRetries = max(*retries, 0)
+	f.SetRetryPolicy(policy)
+	// Cancel in-flight requests on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := f.FetchRelated(ctx, kind, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, skipped := range report.Skipped {
+		fmt.Fprintf(os.Stderr, "Note: skipped %s: %s\n", skipped.Service, skipped.Reason)
+	}
+	if len(report.Aggregates) > 0 {
+		fmt.Fprintf(os.Stderr, "Note: loaded %d aggregates: %s\n", len(report.Aggregates), strings.Join(report.Aggregates, ", "))
+	}
+	if report.DroppedAggregates > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d more aggregates not loaded (limit %d)\n", report.DroppedAggregates, fetcher.MaxRelatedAggregates)
+	}
+	failures := report.Failures()
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", failure.Summary())
+	}
+
+	events, commands := report.Events, report.Commands
+	if match != nil {
+		events, commands = filterRows(match, events, commands)
+	}
+
+	records := BuildRecords(events, commands, *decode)
+	if err := WriteRecords(os.Stdout, records, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(failures) > 0 {
+		return 3
+	}
+	return 0
+}
+
+// filterRows keeps the events and commands matching f
+func filterRows(f *filter.Filter, events []models.Event, commands []models.Command) ([]models.Event, []models.Command) {
+	var keptEvents []models.Event
+	for _, e := range events {
+		if f.MatchEvent(e) {
+			keptEvents = append(keptEvents, e)
+		}
+	}
+	var keptCommands []models.Command
+	for _, c := range commands {
+		if f.MatchCommand(c) {
+			keptCommands = append(keptCommands, c)
+		}
+	}
+	return keptEvents, keptCommands
+}
+
+// WriteRecords renders records to w in the given format
+func WriteRecords(w io.Writer, records []Record, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tTYPE\tSERVICE\tNAME\tSTATUS\tCORRELATION ID")
+		for _, r := range records {
+			kind := "EVT"
+			if r.Type == "command" {
+				kind = "CMD"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.PersistedAt.Format("2006-01-02 15:04:05.000"),
+				kind,
+				r.Service,
+				r.Alias,
+				r.Status,
+				r.CorrelationID,
+			)
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("unknown format %q, expected json, ndjson or table", format)
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"drill/fetcher"
+	"drill/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config is the loaded drill configuration
+type Config struct {
+	Path         string // file the configuration was read from, empty if none
+	Services     []models.ServiceConfig
+	Retry        fetcher.RetryPolicy
+	Warnings     []string // non-fatal problems to show the user
+	Environments []Environment
+	Environment  string                          // name of the selected environment, empty if none
+	Reducers     map[string]models.ReducerConfig // event alias to projection reducer
+
+	base []models.ServiceConfig // services before environment URLs are applied
+}
+
+// FileNames are searched in order, first in the current directory and then
+// in the home directory. The CSV format is deprecated.
+var FileNames = []string{".drill.yaml", ".drill.yml", ".drill.csv"}
+
+// EnvVar names an explicit configuration file, overriding the search
+const EnvVar = "DRILL_CONFIG"
+
+// ErrNotFound is returned when no configuration file exists
+var ErrNotFound = errors.New("no configuration file found")
+
+// Load finds and reads the configuration file. It returns an empty Config
+// and ErrNotFound when there is none.
+func Load() (*Config, error) {
+	if path := os.Getenv(EnvVar); path != "" {
+		return LoadFile(path)
+	}
+
+	var dirs []string
+	dirs = append(dirs, ".")
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+
+	for _, dir := range dirs {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return LoadFile(path)
+			}
+		}
+	}
+
+	return &Config{Retry: fetcher.DefaultRetryPolicy}, ErrNotFound
+}
+
+// LoadFile reads a configuration file, choosing the format by extension
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *Config
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		cfg = parseCSV(data)
+		cfg.Warnings = append([]string{
+			fmt.Sprintf("%s: the CSV format is deprecated, move to .drill.yaml (see .drill.yaml.example)", path),
+		}, cfg.Warnings...)
+	} else {
+		cfg, err = parseYAML(data, filepath.Dir(path))
+		if err != nil {
+			var verrs ValidationErrors
+			if errors.As(err, &verrs) {
+				verrs.File = path
+				return nil, verrs
+			}
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	cfg.Path = path
+	cfg.base = cfg.Services
+	if len(cfg.Environments) > 0 {
+		if err := cfg.UseEnvironment(cfg.Environment); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// ValidationError is a problem at a specific line of the configuration file
+type ValidationError struct {
+	Line    int
+	Message string
+}
+
+// ValidationErrors collects every problem found in a file
+type ValidationErrors struct {
+	File   string
+	Errors []ValidationError
+}
+
+func (e ValidationErrors) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "invalid configuration in %s:", e.File)
+	for _, v := range e.Errors {
+		if v.Line > 0 {
+			fmt.Fprintf(&sb, "\n  line %d: %s", v.Line, v.Message)
+		} else {
+			fmt.Fprintf(&sb, "\n  %s", v.Message)
+		}
+	}
+	return sb.String()
+}
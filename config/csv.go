@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"drill/fetcher"
+	"drill/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseCSV reads the legacy name,idType,url[,option=value...] format.
+// Problems with a line are reported as warnings and the line skipped or
+// defaulted, as before.
+func parseCSV(data []byte) *Config {
+	cfg := &Config{Retry: fetcher.DefaultRetryPolicy}
+	warnf := func(format string, args ...interface{}) {
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	var services []models.ServiceConfig
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Split by comma: name,idType,url[,timeout][,option=value...]
+		parts := strings.Split(line, ",")
+		if len(parts) < 3 {
+			warnf("line %d invalid format '%s', expected 'name,idType,url[,option=value...]'", lineNum, line)
+			continue
+		}
+
+		name := strings.TrimSpace(parts[0])
+		idTypeStr := strings.TrimSpace(parts[1])
+		url := strings.TrimSpace(parts[2])
+
+		var idType models.IDType
+		switch strings.ToLower(idTypeStr) {
+		case "indexid":
+			idType = models.IDTypeIndex
+		case "aggregateid":
+			idType = models.IDTypeAggregate
+		default:
+			warnf("line %d invalid idType '%s', using aggregateId", lineNum, idTypeStr)
+			idType = models.IDTypeAggregate
+		}
+
+		svc := models.ServiceConfig{
+			Name:   name,
+			IDType: idType,
+			URL:    url,
+		}
+
+		for i, part := range parts[3:] {
+			part = strings.TrimSpace(part)
+			key, value, ok := strings.Cut(part, "=")
+			if !ok && i == 0 {
+				// Bare fourth column is a timeout
+				key, value = "timeout", part
+			} else if !ok {
+				warnf("line %d invalid option '%s', expected option=value", lineNum, part)
+				continue
+			}
+			warnings, err := applyServiceOption(&svc, strings.TrimSpace(key), strings.TrimSpace(value))
+			if err != nil {
+				warnf("line %d %v", lineNum, err)
+			}
+			for _, w := range warnings {
+				warnf("line %d %s", lineNum, w)
+			}
+		}
+
+		services = append(services, svc)
+	}
+
+	cfg.Services = services
+	return cfg
+}
+
+// applyServiceOption sets one option=value column on a service, returning
+// any warnings about it
+func applyServiceOption(svc *models.ServiceConfig, key, value string) (warnings []string, err error) {
+	switch {
+	case key == "timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout '%s', using default", value)
+		}
+		svc.Timeout = d
+	case key == "auth":
+		switch models.AuthType(strings.ToLower(value)) {
+		case models.AuthBearer, models.AuthBasic:
+			svc.Auth.Type = models.AuthType(strings.ToLower(value))
+		default:
+			return nil, fmt.Errorf("invalid auth '%s', expected bearer or basic", value)
+		}
+	case key == "token":
+		svc.Auth.Token = models.SecretRef(value)
+		if !svc.Auth.Token.IsReference() {
+			warnings = append(warnings, fmt.Sprintf("%s token is plain text, prefer env:NAME or cmd:command", svc.Name))
+		}
+	case key == "username":
+		svc.Auth.Username = models.SecretRef(value)
+	case key == "password":
+		svc.Auth.Password = models.SecretRef(value)
+		if !svc.Auth.Password.IsReference() {
+			warnings = append(warnings, fmt.Sprintf("%s password is plain text, prefer env:NAME or cmd:command", svc.Name))
+		}
+	case strings.HasPrefix(key, "header."):
+		if svc.Auth.Headers == nil {
+			svc.Auth.Headers = make(map[string]models.SecretRef)
+		}
+		svc.Auth.Headers[strings.TrimPrefix(key, "header.")] = models.SecretRef(value)
+	case key == "paging":
+		switch models.PaginationMode(strings.ToLower(value)) {
+		case models.PaginationNone, models.PaginationCursor, models.PaginationLink:
+			svc.Pagination.Mode = models.PaginationMode(strings.ToLower(value))
+		default:
+			return nil, fmt.Errorf("invalid paging '%s', expected cursor or link", value)
+		}
+	case key == "page_size", key == "max_pages":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %s '%s'", key, value)
+		}
+		if key == "page_size" {
+			svc.Pagination.PageSize = n
+		} else {
+			svc.Pagination.MaxPages = n
+		}
+	case key == "page_size_param":
+		svc.Pagination.PageSizeParam = value
+	case key == "cursor_param":
+		svc.Pagination.CursorParam = value
+	case key == "cursor_field":
+		svc.Pagination.CursorField = value
+	case key == "items_field":
+		svc.Pagination.ItemsField = value
+	case key == "cert":
+		svc.Auth.ClientCert = value
+	case key == "key":
+		svc.Auth.ClientKey = value
+	case key == "ca":
+		svc.Auth.CACert = value
+	case key == "id_format", key == "id_pattern":
+		return nil, fmt.Errorf("%s is not supported in CSV, IDs are checked as UUIDs; use .drill.yaml for idFormat and idPattern", key)
+	default:
+		return nil, fmt.Errorf("unknown option '%s'", key)
+	}
+	return warnings, nil
+}
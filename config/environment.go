@@ -0,0 +1,82 @@
+package config
+
+import (
+	"drill/models"
+	"fmt"
+	"strings"
+)
+
+// Environment is a named set of base URLs for the configured services, such
+// as dev, staging or prod
+type Environment struct {
+	Name       string
+	Production bool              // shown with a warning colour
+	URLs       map[string]string // service name to base URL
+	IDFormat   models.IDFormat   // default for services that set none
+}
+
+// isProductionName reports whether an environment name conventionally
+// means production, used when the file does not say either way
+func isProductionName(name string) bool {
+	switch strings.ToLower(name) {
+	case "prod", "production", "live":
+		return true
+	}
+	return false
+}
+
+// ActiveEnvironment returns the selected environment, or nil when the
+// configuration declares none
+func (c *Config) ActiveEnvironment() *Environment {
+	return c.FindEnvironment(c.Environment)
+}
+
+// FindEnvironment returns the environment with the given name, or nil
+func (c *Config) FindEnvironment(name string) *Environment {
+	for i := range c.Environments {
+		if c.Environments[i].Name == name {
+			return &c.Environments[i]
+		}
+	}
+	return nil
+}
+
+// UseEnvironment selects an environment, pointing Services at its base URLs
+// and ID format. Services the environment does not mention keep their
+// default url, and services with their own idFormat keep it.
+func (c *Config) UseEnvironment(name string) error {
+	env := c.FindEnvironment(name)
+	if env == nil {
+		if len(c.Environments) == 0 {
+			return fmt.Errorf("unknown environment %q, %s declares no environments", name, c.describe())
+		}
+		names := make([]string, len(c.Environments))
+		for i, e := range c.Environments {
+			names[i] = e.Name
+		}
+		return fmt.Errorf("unknown environment %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	services := make([]models.ServiceConfig, len(c.base))
+	for i, svc := range c.base {
+		if u, ok := env.URLs[svc.Name]; ok {
+			svc.URL = u
+		}
+		if svc.IDFormat.Type == "" {
+			svc.IDFormat = env.IDFormat
+		}
+		services[i] = svc
+	}
+
+	c.Services = services
+	c.Environment = env.Name
+	return nil
+}
+
+// describe names the configuration source for error messages
+func (c *Config) describe() string {
+	if c.Path == "" {
+		return "the configuration"
+	}
+	return c.Path
+}
@@ -0,0 +1,721 @@
+package config
+
+import (
+	"bytes"
+	"drill/fetcher"
+	"drill/models"
+	"drill/payload"
+	"drill/projection"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The file layout, mirrored by schema.json. Durations are kept as strings so
+// they can be validated with line numbers.
+
+type fileConfig struct {
+	Retry              *retryConfig        `yaml:"retry"`
+	Services           []serviceConfig     `yaml:"services"`
+	Environments       []environmentConfig `yaml:"environments"`
+	DefaultEnvironment string              `yaml:"defaultEnvironment"`
+	Projection         *projectionConfig   `yaml:"projection"`
+}
+
+type retryConfig struct {
+	MaxRetries *int   `yaml:"maxRetries"`
+	BaseDelay  string `yaml:"baseDelay"`
+	MaxDelay   string `yaml:"maxDelay"`
+}
+
+type serviceConfig struct {
+	Name       string            `yaml:"name"`
+	IDType     stringList        `yaml:"idType"`
+	URL        string            `yaml:"url"`
+	Timeout    string            `yaml:"timeout"`
+	Auth       *authConfig       `yaml:"auth"`
+	Pagination *paginationConfig `yaml:"pagination"`
+	Endpoints  *endpointsConfig  `yaml:"endpoints"`
+	Decoder    *decoderConfig    `yaml:"decoder"`
+	IDFormat   string            `yaml:"idFormat"`
+	IDPattern  string            `yaml:"idPattern"`
+
+	PayloadDecoders []payloadDecoderConfig `yaml:"payloadDecoders"`
+}
+
+// stringList accepts either a single string or a list of strings
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+type environmentConfig struct {
+	Name       string            `yaml:"name"`
+	Production *bool             `yaml:"production"`
+	Services   map[string]string `yaml:"services"` // service name to base URL
+	IDFormat   string            `yaml:"idFormat"`
+	IDPattern  string            `yaml:"idPattern"`
+}
+
+type authConfig struct {
+	Type       string            `yaml:"type"`
+	Token      string            `yaml:"token"`
+	Username   string            `yaml:"username"`
+	Password   string            `yaml:"password"`
+	Headers    map[string]string `yaml:"headers"`
+	ClientCert string            `yaml:"clientCert"`
+	ClientKey  string            `yaml:"clientKey"`
+	CACert     string            `yaml:"caCert"`
+}
+
+type endpointsConfig struct {
+	Events   string            `yaml:"events"`
+	Commands string            `yaml:"commands"`
+	Query    map[string]string `yaml:"query"`
+}
+
+type decoderConfig struct {
+	Type       string            `yaml:"type"`
+	Path       string            `yaml:"path"`
+	Fields     map[string]string `yaml:"fields"`
+	TimeFormat string            `yaml:"timeFormat"`
+}
+
+type projectionConfig struct {
+	Reducers map[string]reducerConfig `yaml:"reducers"` // event alias to reducer
+}
+
+// reducerConfig accepts either a reducer name or a mapping with a path
+type reducerConfig struct {
+	Type string `yaml:"type"`
+	Path string `yaml:"path"`
+}
+
+func (r *reducerConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Type = node.Value
+		return nil
+	}
+	// Decode does not apply KnownFields to custom unmarshalers
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; key.Value != "type" && key.Value != "path" {
+			return fmt.Errorf("line %d: field %s not found in type config.reducerConfig", key.Line, key.Value)
+		}
+	}
+	type plain reducerConfig
+	return node.Decode((*plain)(r))
+}
+
+// payloadDecoderConfig accepts either a step name or a mapping with its
+// settings
+type payloadDecoderConfig struct {
+	Type       string            `yaml:"type"`
+	Descriptor string            `yaml:"descriptor"`
+	Message    string            `yaml:"message"`
+	Schema     string            `yaml:"schema"`
+	Aliases    map[string]string `yaml:"aliases"`
+}
+
+func (p *payloadDecoderConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.Type = node.Value
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch key := node.Content[i]; key.Value {
+		case "type", "descriptor", "message", "schema", "aliases":
+		default:
+			return fmt.Errorf("line %d: field %s not found in type config.payloadDecoderConfig", key.Line, key.Value)
+		}
+	}
+	type plain payloadDecoderConfig
+	return node.Decode((*plain)(p))
+}
+
+type paginationConfig struct {
+	Mode          string `yaml:"mode"`
+	PageSize      int    `yaml:"pageSize"`
+	PageSizeParam string `yaml:"pageSizeParam"`
+	CursorParam   string `yaml:"cursorParam"`
+	CursorField   string `yaml:"cursorField"`
+	ItemsField    string `yaml:"itemsField"`
+	MaxPages      int    `yaml:"maxPages"`
+}
+
+// yamlLineRe pulls the line number out of yaml.v3 error messages
+var yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// parseYAML reads a YAML configuration. Relative file paths in it are
+// resolved against dir.
+func parseYAML(data []byte, dir string) (*Config, error) {
+	var file fileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, decodeErrors(err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, decodeErrors(err)
+	}
+
+	v := &validator{root: &root, dir: dir, environments: len(file.Environments) > 0}
+	cfg := &Config{Retry: fetcher.DefaultRetryPolicy}
+
+	if file.Retry != nil {
+		v.retry(file.Retry, &cfg.Retry)
+	}
+
+	if len(file.Services) == 0 {
+		v.errorf(v.line("services"), "no services defined")
+	}
+
+	seen := make(map[string]int)
+	for i, raw := range file.Services {
+		svc := v.service(i, raw)
+		if prev, ok := seen[svc.Name]; ok && svc.Name != "" {
+			v.errorf(v.line("services", i, "name"), "duplicate service name %q (first defined on line %d)", svc.Name, prev)
+		} else {
+			seen[svc.Name] = v.line("services", i, "name")
+		}
+		cfg.Services = append(cfg.Services, svc)
+	}
+
+	seen = make(map[string]int)
+	for i, raw := range file.Environments {
+		env := v.environment(i, raw, cfg.Services)
+		if prev, ok := seen[env.Name]; ok && env.Name != "" {
+			v.errorf(v.line("environments", i, "name"), "duplicate environment name %q (first defined on line %d)", env.Name, prev)
+		} else {
+			seen[env.Name] = v.line("environments", i, "name")
+		}
+		cfg.Environments = append(cfg.Environments, env)
+	}
+	v.defaultEnvironment(file.DefaultEnvironment, cfg)
+
+	if file.Projection != nil {
+		cfg.Reducers = v.reducers(file.Projection.Reducers)
+	}
+
+	if len(v.errs) > 0 {
+		sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Line < v.errs[j].Line })
+		return nil, ValidationErrors{Errors: v.errs}
+	}
+
+	cfg.Warnings = v.warnings
+	return cfg, nil
+}
+
+// decodeErrors converts yaml.v3 errors into ValidationErrors
+func decodeErrors(err error) error {
+	var lines []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		lines = typeErr.Errors
+	} else {
+		lines = []string{err.Error()}
+	}
+
+	var verrs ValidationErrors
+	for _, l := range lines {
+		if m := yamlLineRe.FindStringSubmatch(l); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			verrs.Errors = append(verrs.Errors, ValidationError{Line: n, Message: m[2]})
+			continue
+		}
+		verrs.Errors = append(verrs.Errors, ValidationError{Message: strings.TrimPrefix(l, "yaml: ")})
+	}
+	return verrs
+}
+
+type validator struct {
+	root         *yaml.Node
+	dir          string // directory relative file paths are resolved against
+	environments bool   // service urls may come from environments instead
+	errs         []ValidationError
+	warnings     []string
+}
+
+func (v *validator) errorf(line int, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(line int, format string, args ...interface{}) {
+	v.warnings = append(v.warnings, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+}
+
+// line returns the line of the node at path, where each element is a
+// mapping key (string) or sequence index (int). If the path does not exist
+// it returns the line of the deepest node that does.
+func (v *validator) line(path ...interface{}) int {
+	node := v.root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := node.Line
+
+	for _, p := range path {
+		var next *yaml.Node
+		switch key := p.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind != yaml.SequenceNode || key >= len(node.Content) {
+				return line
+			}
+			next = node.Content[key]
+			line = next.Line
+		}
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+
+	return line
+}
+
+func (v *validator) duration(value string, path ...interface{}) time.Duration {
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		v.errorf(v.line(path...), "invalid duration %q, expected e.g. 500ms or 10s", value)
+		return 0
+	}
+	return d
+}
+
+func (v *validator) retry(raw *retryConfig, policy *fetcher.RetryPolicy) {
+	if raw.MaxRetries != nil {
+		if *raw.MaxRetries < 0 {
+			v.errorf(v.line("retry", "maxRetries"), "maxRetries cannot be negative")
+		} else {
+			policy.MaxRetries = *raw.MaxRetries
+		}
+	}
+	if d := v.duration(raw.BaseDelay, "retry", "baseDelay"); d > 0 {
+		policy.BaseDelay = d
+	}
+	if d := v.duration(raw.MaxDelay, "retry", "maxDelay"); d > 0 {
+		policy.MaxDelay = d
+	}
+}
+
+func (v *validator) service(i int, raw serviceConfig) models.ServiceConfig {
+	at := func(path ...interface{}) int {
+		return v.line(append([]interface{}{"services", i}, path...)...)
+	}
+
+	svc := models.ServiceConfig{
+		Name: strings.TrimSpace(raw.Name),
+		URL:  strings.TrimRight(strings.TrimSpace(raw.URL), "/"),
+	}
+
+	if svc.Name == "" {
+		v.errorf(at(), "service is missing a name")
+	}
+
+	if len(raw.IDType) == 0 {
+		v.errorf(at(), "service %q is missing idType", svc.Name)
+	}
+	kinds := make(map[models.LookupKind]models.IDType)
+	for _, name := range raw.IDType {
+		var idType models.IDType
+		for _, t := range []models.IDType{models.IDTypeAggregate, models.IDTypeIndex, models.IDTypeCorrelation, models.IDTypeCommand} {
+			if strings.EqualFold(name, string(t)) {
+				idType = t
+			}
+		}
+		if idType == "" {
+			v.errorf(at("idType"), "invalid idType %q, expected aggregateId, indexId, correlationId or commandId", name)
+			continue
+		}
+		if prev, ok := kinds[idType.Kind()]; ok {
+			v.errorf(at("idType"), "idType lists both %s and %s, which are the same kind of lookup", prev, idType)
+			continue
+		}
+		kinds[idType.Kind()] = idType
+		if idType.Kind() == models.LookupAggregate {
+			svc.IDType = idType
+		} else {
+			svc.IDTypes = append(svc.IDTypes, idType)
+		}
+	}
+
+	if svc.URL == "" {
+		if !v.environments {
+			v.errorf(at(), "service %q is missing url", svc.Name)
+		}
+	} else if !validURL(svc.URL) {
+		v.errorf(at("url"), "invalid url %q, expected http(s)://host", raw.URL)
+	}
+
+	svc.Timeout = v.duration(raw.Timeout, "services", i, "timeout")
+
+	if raw.Auth != nil {
+		svc.Auth = v.auth(raw.Auth, at)
+	}
+	if raw.Pagination != nil {
+		svc.Pagination = v.pagination(raw.Pagination, at)
+	}
+	if raw.Endpoints != nil {
+		svc.Endpoints = v.endpoints(raw.Endpoints, at)
+	}
+	if raw.Decoder != nil {
+		svc.Decoder = v.decoder(raw.Decoder, at)
+	}
+	svc.IDFormat = v.idFormat(raw.IDFormat, raw.IDPattern, at)
+	svc.Payload = v.payloadDecoders(raw.PayloadDecoders, at)
+
+	return svc
+}
+
+// validURL reports whether raw is an absolute http(s) URL with a host
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (v *validator) environment(i int, raw environmentConfig, services []models.ServiceConfig) Environment {
+	at := func(path ...interface{}) int {
+		return v.line(append([]interface{}{"environments", i}, path...)...)
+	}
+
+	env := Environment{
+		Name:       strings.TrimSpace(raw.Name),
+		Production: isProductionName(raw.Name),
+		URLs:       make(map[string]string, len(raw.Services)),
+	}
+	if raw.Production != nil {
+		env.Production = *raw.Production
+	}
+	env.IDFormat = v.idFormat(raw.IDFormat, raw.IDPattern, at)
+
+	if env.Name == "" {
+		v.errorf(at(), "environment is missing a name")
+	}
+
+	known := make(map[string]bool, len(services))
+	for _, svc := range services {
+		known[svc.Name] = true
+	}
+
+	for name, u := range raw.Services {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		switch {
+		case !known[name]:
+			v.errorf(at("services", name), "environment %q sets a url for unknown service %q", env.Name, name)
+		case !validURL(u):
+			v.errorf(at("services", name), "invalid url %q, expected http(s)://host", raw.Services[name])
+		}
+		env.URLs[name] = u
+	}
+
+	for _, svc := range services {
+		if _, ok := env.URLs[svc.Name]; !ok && svc.URL == "" && svc.Name != "" {
+			v.errorf(at("services"), "environment %q has no url for service %q", env.Name, svc.Name)
+		}
+	}
+
+	return env
+}
+
+// defaultEnvironment checks the defaultEnvironment key and records the
+// environment to select, falling back to the first one declared
+func (v *validator) defaultEnvironment(name string, cfg *Config) {
+	if len(cfg.Environments) == 0 {
+		if name != "" {
+			v.errorf(v.line("defaultEnvironment"), "defaultEnvironment %q set but no environments are defined", name)
+		}
+		return
+	}
+
+	if name == "" {
+		cfg.Environment = cfg.Environments[0].Name
+		return
+	}
+	if cfg.FindEnvironment(name) == nil {
+		v.errorf(v.line("defaultEnvironment"), "defaultEnvironment %q is not a defined environment", name)
+		return
+	}
+	cfg.Environment = name
+}
+
+func (v *validator) auth(raw *authConfig, at func(...interface{}) int) models.AuthConfig {
+	auth := models.AuthConfig{
+		Token:      models.SecretRef(raw.Token),
+		Username:   models.SecretRef(raw.Username),
+		Password:   models.SecretRef(raw.Password),
+		ClientCert: v.path(raw.ClientCert),
+		ClientKey:  v.path(raw.ClientKey),
+		CACert:     v.path(raw.CACert),
+	}
+
+	switch models.AuthType(strings.ToLower(raw.Type)) {
+	case models.AuthNone:
+	case models.AuthBearer:
+		auth.Type = models.AuthBearer
+		if raw.Token == "" {
+			v.errorf(at("auth"), "bearer auth requires a token")
+		}
+	case models.AuthBasic:
+		auth.Type = models.AuthBasic
+		if raw.Username == "" || raw.Password == "" {
+			v.errorf(at("auth"), "basic auth requires a username and password")
+		}
+	default:
+		v.errorf(at("auth", "type"), "invalid auth type %q, expected bearer or basic", raw.Type)
+	}
+
+	if raw.Token != "" && !auth.Token.IsReference() {
+		v.warnf(at("auth", "token"), "token is plain text, prefer env:NAME or cmd:command")
+	}
+	if raw.Password != "" && !auth.Password.IsReference() {
+		v.warnf(at("auth", "password"), "password is plain text, prefer env:NAME or cmd:command")
+	}
+
+	if len(raw.Headers) > 0 {
+		auth.Headers = make(map[string]models.SecretRef, len(raw.Headers))
+		for name, value := range raw.Headers {
+			auth.Headers[name] = models.SecretRef(value)
+		}
+	}
+
+	if (raw.ClientCert == "") != (raw.ClientKey == "") {
+		v.errorf(at("auth"), "clientCert and clientKey must be set together")
+	}
+
+	return auth
+}
+
+func (v *validator) pagination(raw *paginationConfig, at func(...interface{}) int) models.PaginationConfig {
+	p := models.PaginationConfig{
+		PageSize:      raw.PageSize,
+		PageSizeParam: raw.PageSizeParam,
+		CursorParam:   raw.CursorParam,
+		CursorField:   raw.CursorField,
+		ItemsField:    raw.ItemsField,
+		MaxPages:      raw.MaxPages,
+	}
+
+	switch models.PaginationMode(strings.ToLower(raw.Mode)) {
+	case models.PaginationNone:
+	case models.PaginationCursor:
+		p.Mode = models.PaginationCursor
+	case models.PaginationLink:
+		p.Mode = models.PaginationLink
+	default:
+		v.errorf(at("pagination", "mode"), "invalid pagination mode %q, expected cursor or link", raw.Mode)
+	}
+
+	if raw.PageSize < 0 {
+		v.errorf(at("pagination", "pageSize"), "pageSize cannot be negative")
+	}
+	if raw.MaxPages < 0 {
+		v.errorf(at("pagination", "maxPages"), "maxPages cannot be negative")
+	}
+
+	return p
+}
+
+func (v *validator) endpoints(raw *endpointsConfig, at func(...interface{}) int) models.EndpointsConfig {
+	e := models.EndpointsConfig{
+		Events:   strings.TrimSpace(raw.Events),
+		Commands: strings.TrimSpace(raw.Commands),
+		Query:    raw.Query,
+	}
+
+	queryHasID := false
+	for name, value := range raw.Query {
+		v.placeholders(value, at("endpoints", "query", name))
+		if strings.Contains(value, "{id}") {
+			queryHasID = true
+		}
+	}
+
+	for _, t := range []struct{ key, tmpl string }{{"events", e.Events}, {"commands", e.Commands}} {
+		if t.tmpl == "" {
+			continue
+		}
+		line := at("endpoints", t.key)
+		if strings.Contains(t.tmpl, "://") {
+			v.errorf(line, "%s endpoint %q must be a path relative to the service url", t.key, t.tmpl)
+			continue
+		}
+		v.placeholders(t.tmpl, line)
+		if !queryHasID && !strings.Contains(t.tmpl, "{id}") {
+			v.errorf(line, "%s endpoint %q has no {id} placeholder", t.key, t.tmpl)
+		}
+	}
+
+	return e
+}
+
+// placeholders reports any {name} in s that endpoint templates do not support
+func (v *validator) placeholders(s string, line int) {
+	for _, name := range fetcher.TemplatePlaceholders(s) {
+		known := false
+		for _, p := range fetcher.Placeholders {
+			known = known || p == name
+		}
+		if !known {
+			v.errorf(line, "unknown placeholder {%s}, expected {id} or {idType}", name)
+		}
+	}
+}
+
+func (v *validator) decoder(raw *decoderConfig, at func(...interface{}) int) models.DecoderConfig {
+	d := models.DecoderConfig{
+		Type:       strings.TrimSpace(raw.Type),
+		Path:       strings.TrimSpace(raw.Path),
+		Fields:     raw.Fields,
+		TimeFormat: strings.TrimSpace(raw.TimeFormat),
+	}
+
+	for name := range raw.Fields {
+		_, isEvent := fetcher.EventFields[name]
+		_, isCommand := fetcher.CommandFields[name]
+		if !isEvent && !isCommand {
+			v.errorf(at("decoder", "fields", name), "unknown field %q, expected one of the event or command field names", name)
+		}
+	}
+
+	switch d.TimeFormat {
+	case "", "rfc3339", "unix", "unixmilli", "unixmicro", "unixnano":
+	default:
+		if !strings.Contains(d.TimeFormat, "2006") {
+			v.errorf(at("decoder", "timeFormat"), "invalid timeFormat %q, expected rfc3339, unix, unixmilli, unixmicro, unixnano or a Go layout such as 2006-01-02 15:04:05", raw.TimeFormat)
+		}
+	}
+
+	if d.Type != "" {
+		if _, err := fetcher.NewDecoder(models.DecoderConfig{Type: d.Type}); err != nil {
+			v.errorf(at("decoder", "type"), "%v", err)
+		}
+	}
+
+	return d
+}
+
+// reducers checks the projection reducers configured per event alias
+func (v *validator) reducers(raw map[string]reducerConfig) map[string]models.ReducerConfig {
+	reducers := make(map[string]models.ReducerConfig, len(raw))
+	for alias, r := range raw {
+		cfg := models.ReducerConfig{
+			Type: strings.TrimSpace(r.Type),
+			Path: strings.Trim(strings.TrimSpace(r.Path), "."),
+		}
+		if _, err := projection.NewReducer(cfg); err != nil {
+			v.errorf(v.line("projection", "reducers", alias), "%v", err)
+		}
+		reducers[alias] = cfg
+	}
+	return reducers
+}
+
+// payloadDecoders checks a service's payload decoding steps, loading their
+// descriptor and schema files
+func (v *validator) payloadDecoders(raw []payloadDecoderConfig, at func(...interface{}) int) []models.PayloadDecoderConfig {
+	var steps []models.PayloadDecoderConfig
+	for i, r := range raw {
+		cfg := models.PayloadDecoderConfig{
+			Type:       strings.ToLower(strings.TrimSpace(r.Type)),
+			Descriptor: v.path(r.Descriptor),
+			Message:    strings.TrimSpace(r.Message),
+			Schema:     v.path(r.Schema),
+		}
+		if cfg.Type == "" {
+			v.errorf(at("payloadDecoders", i), "payload decoder is missing a type")
+			continue
+		}
+		if len(r.Aliases) > 0 {
+			cfg.Aliases = make(map[string]string, len(r.Aliases))
+			for alias, value := range r.Aliases {
+				if cfg.Type == "avro" {
+					value = v.path(value)
+				}
+				cfg.Aliases[alias] = strings.TrimSpace(value)
+			}
+		}
+		if _, err := payload.NewStep(cfg); err != nil {
+			v.errorf(at("payloadDecoders", i), "%v", err)
+		}
+		steps = append(steps, cfg)
+	}
+	return steps
+}
+
+// path expands a leading ~ to the home directory and resolves a relative
+// file path against the configuration file's directory
+func (v *validator) path(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(v.dir, p)
+}
+
+// idFormat checks an idFormat/idPattern pair. A pattern on its own implies
+// the regex format.
+func (v *validator) idFormat(format, pattern string, at func(...interface{}) int) models.IDFormat {
+	f := models.IDFormat{Type: models.IDFormatType(strings.ToLower(strings.TrimSpace(format))), Pattern: pattern}
+	if f.Type == "" && pattern != "" {
+		f.Type = models.IDFormatRegex
+	}
+
+	switch f.Type {
+	case "", models.IDFormatUUID, models.IDFormatULID, models.IDFormatNone:
+		if pattern != "" {
+			v.errorf(at("idPattern"), "idPattern is only used with idFormat: regex")
+		}
+	case models.IDFormatRegex:
+		if pattern == "" {
+			v.errorf(at("idFormat"), "idFormat regex requires an idPattern")
+		} else if re, err := fetcher.CompileIDPattern(pattern); err != nil {
+			v.errorf(at("idPattern"), "invalid idPattern: %v", err)
+		} else {
+			f.Regexp = re
+		}
+	default:
+		v.errorf(at("idFormat"), "invalid idFormat %q, expected uuid, ulid, regex or none", format)
+	}
+
+	return f
+}
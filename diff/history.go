@@ -0,0 +1,214 @@
+package diff
+
+import (
+	"drill/models"
+	"sort"
+	"time"
+)
+
+// Entry is the part of an event or command that histories are compared on
+type Entry struct {
+	ID          string
+	Alias       string
+	Status      string // commands only
+	Service     string
+	Payload     string
+	PersistedAt time.Time
+}
+
+// EventEntries converts events for Histories, in history order
+func EventEntries(events []models.Event) []Entry {
+	entries := make([]Entry, len(events))
+	for i, e := range events {
+		entries[i] = Entry{
+			ID:          e.Metadata.EventID,
+			Alias:       e.Metadata.EventAlias,
+			Service:     e.ServiceName,
+			Payload:     e.Payload,
+			PersistedAt: e.Metadata.PersistedAt,
+		}
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// CommandEntries converts commands for Histories, in history order
+func CommandEntries(commands []models.Command) []Entry {
+	entries := make([]Entry, len(commands))
+	for i, c := range commands {
+		entries[i] = Entry{
+			ID:          c.CommandID,
+			Alias:       c.CommandAlias,
+			Status:      string(c.CommandStatus),
+			Service:     c.ServiceName,
+			Payload:     c.Payload,
+			PersistedAt: c.PersistedAt,
+		}
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// sortEntries orders entries by persistedAt, then service and ID. Cached
+// data is in the order fetches completed, which differs between runs.
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.PersistedAt.Equal(b.PersistedAt) {
+			return a.PersistedAt.Before(b.PersistedAt)
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.ID < b.ID
+	})
+}
+
+// Row pairs an entry from the left history with its counterpart on the
+// right. Kind is Added when only Right is set, Removed when only Left is set,
+// Changed when both are set but differ, and zero when they are the same.
+type Row struct {
+	Kind    Kind
+	Left    *Entry
+	Right   *Entry
+	Changes []Change // payload differences when both sides are set
+}
+
+// AliasChanged reports whether both sides are set with different aliases
+func (r Row) AliasChanged() bool {
+	return r.Left != nil && r.Right != nil && r.Left.Alias != r.Right.Alias
+}
+
+// StatusChanged reports whether both sides are set with different statuses
+func (r Row) StatusChanged() bool {
+	return r.Left != nil && r.Right != nil && r.Left.Status != r.Right.Status
+}
+
+// maxAlignCells bounds the alignment table; larger histories that differ
+// in the middle are compared by position instead
+const maxAlignCells = 4_000_000
+
+// Histories lines up two ordered histories. Entries are matched on alias so
+// an inserted or dropped entry does not shift every later one; unmatched
+// entries between two matches are paired by position and reported as
+// Changed, and any left over are Added or Removed.
+func Histories(left, right []Entry) []Row {
+	// Replays usually agree at both ends, so only align the middle
+	prefix := 0
+	for prefix < len(left) && prefix < len(right) && left[prefix].Alias == right[prefix].Alias {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(left)-prefix && suffix < len(right)-prefix &&
+		left[len(left)-1-suffix].Alias == right[len(right)-1-suffix].Alias {
+		suffix++
+	}
+
+	var rows []Row
+	for i := 0; i < prefix; i++ {
+		rows = append(rows, pair(&left[i], &right[i]))
+	}
+	rows = append(rows, alignMiddle(left[prefix:len(left)-suffix], right[prefix:len(right)-suffix])...)
+	for i := suffix; i > 0; i-- {
+		rows = append(rows, pair(&left[len(left)-i], &right[len(right)-i]))
+	}
+	return rows
+}
+
+// alignMiddle matches entries by the longest common subsequence of aliases
+func alignMiddle(left, right []Entry) []Row {
+	n, m := len(left), len(right)
+	if n == 0 || m == 0 || n*m > maxAlignCells {
+		return pairRun(left, right)
+	}
+
+	// lcs[i][j] is the common subsequence length of left[i:] and right[j:]
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if left[i].Alias == right[j].Alias {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var rows []Row
+	i, j := 0, 0
+	li, rj := 0, 0 // start of the unmatched run on each side
+	for i < n && j < m {
+		switch {
+		case left[i].Alias == right[j].Alias:
+			rows = append(rows, pairRun(left[li:i], right[rj:j])...)
+			rows = append(rows, pair(&left[i], &right[j]))
+			i++
+			j++
+			li, rj = i, j
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return append(rows, pairRun(left[li:], right[rj:])...)
+}
+
+// pairRun pairs unmatched entries by position, leaving the longer side's
+// extra entries unpaired
+func pairRun(left, right []Entry) []Row {
+	var rows []Row
+	for k := 0; k < max(len(left), len(right)); k++ {
+		switch {
+		case k >= len(left):
+			rows = append(rows, Row{Kind: Added, Right: &right[k]})
+		case k >= len(right):
+			rows = append(rows, Row{Kind: Removed, Left: &left[k]})
+		default:
+			rows = append(rows, pair(&left[k], &right[k]))
+		}
+	}
+	return rows
+}
+
+func pair(left, right *Entry) Row {
+	row := Row{Left: left, Right: right, Changes: Payloads(left.Payload, right.Payload)}
+	if len(row.Changes) > 0 || row.AliasChanged() || row.StatusChanged() {
+		row.Kind = Changed
+	}
+	return row
+}
+
+// Summary counts rows by kind
+type Summary struct {
+	Same    int
+	Changed int
+	Added   int
+	Removed int
+}
+
+// Summarize counts rows by kind
+func Summarize(rows []Row) Summary {
+	var s Summary
+	for _, r := range rows {
+		switch r.Kind {
+		case Added:
+			s.Added++
+		case Removed:
+			s.Removed++
+		case Changed:
+			s.Changed++
+		default:
+			s.Same++
+		}
+	}
+	return s
+}
+
+// Identical reports whether no row differs
+func (s Summary) Identical() bool {
+	return s.Changed == 0 && s.Added == 0 && s.Removed == 0
+}
@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"drill/models"
+	"testing"
+	"time"
+)
+
+func event(service, id, alias string, at time.Time) models.Event {
+	return models.Event{
+		Metadata:    models.EventMetadata{EventID: id, EventAlias: alias, PersistedAt: at},
+		Payload:     `{}`,
+		ServiceName: service,
+	}
+}
+
+func TestEntriesOrderAcrossServices(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Second)
+
+	// The same history as fetched twice, with services finishing in a
+	// different order each time
+	left := []models.Event{
+		event("orders", "2", "OrderPlaced", t0),
+		event("billing", "9", "InvoiceCreated", t0),
+		event("billing", "1", "InvoicePaid", t1),
+		event("orders", "1", "OrderPaid", t1),
+	}
+	right := []models.Event{
+		event("billing", "1", "InvoicePaid", t1),
+		event("orders", "1", "OrderPaid", t1),
+		event("orders", "2", "OrderPlaced", t0),
+		event("billing", "9", "InvoiceCreated", t0),
+	}
+
+	want := []string{"billing/9", "orders/2", "billing/1", "orders/1"}
+	for name, events := range map[string][]models.Event{"left": left, "right": right} {
+		entries := EventEntries(events)
+		for i, e := range entries {
+			if got := e.Service + "/" + e.ID; got != want[i] {
+				t.Errorf("%s entry %d = %s, want %s", name, i, got, want[i])
+			}
+		}
+	}
+
+	for _, row := range Histories(EventEntries(left), EventEntries(right)) {
+		if row.Kind != 0 {
+			t.Errorf("row %s/%s is %v, want the same history on both sides", row.Left.Service, row.Left.ID, row.Kind)
+		}
+	}
+}
+
+func TestCommandEntriesOrder(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commands := []models.Command{
+		{CommandID: "b", PersistedAt: t0, ServiceName: "orders"},
+		{CommandID: "a", PersistedAt: t0, ServiceName: "orders"},
+		{CommandID: "z", PersistedAt: t0, ServiceName: "billing"},
+		{CommandID: "c", PersistedAt: t0.Add(-time.Second), ServiceName: "shipping"},
+	}
+	want := []string{"c", "z", "a", "b"}
+	for i, e := range CommandEntries(commands) {
+		if e.ID != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, e.ID, want[i])
+		}
+	}
+}
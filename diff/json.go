@@ -0,0 +1,140 @@
+// Package diff compares payloads field by field and event and command
+// histories entry by entry.
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind says how a field or history entry differs
+type Kind int
+
+const (
+	Added Kind = iota + 1
+	Removed
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return "same"
+}
+
+// Change is one field that differs between two JSON values. Path is dotted
+// with array indexes in brackets, e.g. items[0].sku, and empty for the root.
+// Old is unset for Added, New for Removed.
+type Change struct {
+	Path string
+	Kind Kind
+	Old  interface{}
+	New  interface{}
+}
+
+// Parse decodes a JSON payload keeping numbers exact. A payload that is not
+// JSON is returned as a plain string so it still compares by value.
+func Parse(payload string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(payload))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return payload
+	}
+	return v
+}
+
+// Payloads compares two JSON payloads
+func Payloads(before, after string) []Change {
+	return Values(Parse(before), Parse(after))
+}
+
+// Values compares two decoded JSON values, listing changes in path order
+func Values(before, after interface{}) []Change {
+	var changes []Change
+	compare("", before, after, &changes)
+	return changes
+}
+
+func compare(path string, before, after interface{}, changes *[]Change) {
+	switch o := before.(type) {
+	case map[string]interface{}:
+		if n, ok := after.(map[string]interface{}); ok {
+			for _, key := range unionKeys(o, n) {
+				ov, inOld := o[key]
+				nv, inNew := n[key]
+				p := join(path, key)
+				switch {
+				case !inOld:
+					*changes = append(*changes, Change{Path: p, Kind: Added, New: nv})
+				case !inNew:
+					*changes = append(*changes, Change{Path: p, Kind: Removed, Old: ov})
+				default:
+					compare(p, ov, nv, changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if n, ok := after.([]interface{}); ok {
+			for i := 0; i < max(len(o), len(n)); i++ {
+				p := path + "[" + strconv.Itoa(i) + "]"
+				switch {
+				case i >= len(o):
+					*changes = append(*changes, Change{Path: p, Kind: Added, New: n[i]})
+				case i >= len(n):
+					*changes = append(*changes, Change{Path: p, Kind: Removed, Old: o[i]})
+				default:
+					compare(p, o[i], n[i], changes)
+				}
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, Change{Path: path, Kind: Changed, Old: before, New: after})
+	}
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Format renders a decoded value compactly for display
+func Format(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "?"
+	}
+	return string(b)
+}
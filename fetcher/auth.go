@@ -0,0 +1,160 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"drill/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAuth wraps failures to resolve credentials or load certificates. These
+// are configuration problems, so they are neither retried nor counted
+// against the service's breaker.
+var ErrAuth = errors.New("auth")
+
+// secretCache holds resolved secrets for the session so commands such as
+// `pass show ...` run once rather than on every request. Failures are not
+// cached, so the next request tries again.
+var secretCache sync.Map
+
+// secretWaitDelay bounds how long a cancelled secret command's children may
+// hold its output open, e.g. a gpg agent started by pass
+const secretWaitDelay = time.Second
+
+// ResolveSecret returns the value of a secret reference. Commands are
+// killed when ctx is done, and must print a non-empty secret.
+func ResolveSecret(ctx context.Context, ref models.SecretRef) (string, error) {
+	s := string(ref)
+	if !ref.IsReference() {
+		return s, nil
+	}
+	if v, ok := secretCache.Load(s); ok {
+		return v.(string), nil
+	}
+
+	var value string
+	switch {
+	case strings.HasPrefix(s, "env:"):
+		name := strings.TrimPrefix(s, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		value = v
+
+	case strings.HasPrefix(s, "cmd:"):
+		command := strings.TrimPrefix(s, "cmd:")
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Stderr = &stderr
+		cmd.WaitDelay = secretWaitDelay
+		out, err := cmd.Output()
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("secret command %q stopped: %w", command, ctx.Err())
+		}
+		if err != nil {
+			return "", fmt.Errorf("secret command %q failed: %v %s", command, err, strings.TrimSpace(stderr.String()))
+		}
+		// Like pass, only the first line is the secret
+		value, _, _ = strings.Cut(string(out), "\n")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return "", fmt.Errorf("secret command %q printed nothing", command)
+		}
+	}
+
+	secretCache.Store(s, value)
+	return value, nil
+}
+
+// authorize adds the service's credentials and custom headers to req.
+// Secrets are resolved within req's context, so they share its timeout.
+func authorize(req *http.Request, auth models.AuthConfig) error {
+	ctx := req.Context()
+	for name, ref := range auth.Headers {
+		value, err := ResolveSecret(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("%w: header %s: %w", ErrAuth, name, err)
+		}
+		req.Header.Set(name, value)
+	}
+
+	switch auth.Type {
+	case models.AuthBearer:
+		token, err := ResolveSecret(ctx, auth.Token)
+		if err != nil {
+			return fmt.Errorf("%w: token: %w", ErrAuth, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+	case models.AuthBasic:
+		username, err := ResolveSecret(ctx, auth.Username)
+		if err != nil {
+			return fmt.Errorf("%w: username: %w", ErrAuth, err)
+		}
+		password, err := ResolveSecret(ctx, auth.Password)
+		if err != nil {
+			return fmt.Errorf("%w: password: %w", ErrAuth, err)
+		}
+		req.SetBasicAuth(username, password)
+	}
+
+	return nil
+}
+
+// clientFor returns the HTTP client for a service, building one with client
+// certificates when the service uses mTLS
+func (f *Fetcher) clientFor(service models.ServiceConfig) (*http.Client, error) {
+	auth := service.Auth
+	if auth.ClientCert == "" && auth.CACert == "" {
+		return f.client, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if c, ok := f.tlsClients[service.Name]; ok {
+		return c, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if auth.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(auth.ClientCert, auth.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: client certificate: %w", ErrAuth, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if auth.CACert != "" {
+		pem, err := os.ReadFile(auth.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("%w: CA certificate: %w", ErrAuth, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificates found in %s", ErrAuth, auth.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	c := &http.Client{Transport: transport}
+	if f.tlsClients == nil {
+		f.tlsClients = make(map[string]*http.Client)
+	}
+	f.tlsClients[service.Name] = c
+	return c, nil
+}
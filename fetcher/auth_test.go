@@ -0,0 +1,72 @@
+package fetcher
+
+import (
+	"context"
+	"drill/models"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveSecretCommand(t *testing.T) {
+	ctx := context.Background()
+
+	got, err := ResolveSecret(ctx, "cmd:printf 'tok3n\\nmetadata\\n'")
+	if err != nil || got != "tok3n" {
+		t.Fatalf("got %q, %v, want the first line", got, err)
+	}
+
+	if _, err := ResolveSecret(ctx, "cmd:true"); err == nil || !strings.Contains(err.Error(), "printed nothing") {
+		t.Fatalf("err = %v, want an error for empty output", err)
+	}
+	if _, err := ResolveSecret(ctx, "cmd:exit 3"); err == nil {
+		t.Fatal("failing command resolved")
+	}
+}
+
+func TestResolveSecretFailuresNotCached(t *testing.T) {
+	flag := filepath.Join(t.TempDir(), "ready")
+	ref := models.SecretRef("cmd:cat " + flag + " 2>/dev/null || true")
+
+	if _, err := ResolveSecret(context.Background(), ref); err == nil {
+		t.Fatal("expected an error before the secret exists")
+	}
+	if err := os.WriteFile(flag, []byte("later\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ResolveSecret(context.Background(), ref)
+	if err != nil || got != "later" {
+		t.Fatalf("got %q, %v after the secret appeared", got, err)
+	}
+}
+
+func TestResolveSecretHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := ResolveSecret(ctx, "cmd:sleep 10")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("hung command held the caller for %s", elapsed)
+	}
+}
+
+func TestAuthorizeUsesRequestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = authorize(req, models.AuthConfig{Type: models.AuthBearer, Token: "cmd:sleep 10; echo late"})
+	if !errors.Is(err, ErrAuth) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want an auth error from the cancelled request", err)
+	}
+}
@@ -0,0 +1,124 @@
+package fetcher
+
+import (
+	"drill/models"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without making a request once a service's
+// breaker has tripped
+var ErrCircuitOpen = errors.New("circuit open, service marked down")
+
+// BreakerThreshold is the number of consecutive fetches with a failed call
+// that trips a service's breaker. A fetch counts once however many of its
+// calls fail.
+const BreakerThreshold = 2
+
+// BreakerCooldown is how long a tripped breaker fails fast before letting
+// one fetch through to probe whether the service has recovered
+const BreakerCooldown = 30 * time.Second
+
+// SessionBreakers is shared by every fetcher so a service that is known to
+// be down fails fast across fetches until a probe finds it back up
+var SessionBreakers = NewBreakerSet()
+
+// BreakerSet tracks one circuit breaker per service and base URL, so a
+// service that is down in one environment is still tried in another
+type BreakerSet struct {
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func NewBreakerSet() *BreakerSet {
+	return &BreakerSet{breakers: make(map[string]*breaker)}
+}
+
+func (s *BreakerSet) forService(service models.ServiceConfig) *breaker {
+	key := service.Name + " " + service.URL
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[key]
+	if !ok {
+		b = &breaker{}
+		s.breakers[key] = b
+	}
+	return b
+}
+
+// breaker is closed while openedAt is zero. Once open it rejects calls
+// until the cooldown has passed, then admits a single probing fetch whose
+// success closes it and whose failure opens it again. A probe that ends
+// any other way, e.g. cancelled or with a 404, is released so that the
+// next fetch probes instead.
+type breaker struct {
+	mu          sync.Mutex
+	failures    int    // consecutive fetches with a failed call
+	lastFailure uint64 // fetch that last counted, so each fetch counts once
+	openedAt    time.Time
+	probe       uint64 // fetch admitted after the cooldown, 0 if none yet
+}
+
+// allow reports whether a call made by fetch may go ahead
+func (b *breaker) allow(fetch uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero(), b.probe == fetch:
+		return true
+	case b.probe == 0 && time.Since(b.openedAt) >= BreakerCooldown:
+		b.probe = fetch
+		return true
+	}
+	return false
+}
+
+// release gives up fetch's probe, if it holds one, once a call ends
+// without recording a success or failure
+func (b *breaker) release(fetch uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.probe == fetch {
+		b.probe = 0
+	}
+}
+
+func (b *breaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openedAt = time.Time{}
+	b.probe = 0
+}
+
+func (b *breaker) recordFailure(fetch uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.openedAt.IsZero() {
+		if b.probe == fetch {
+			b.openedAt, b.probe = time.Now(), 0
+		}
+		return
+	}
+	if b.lastFailure == fetch {
+		return
+	}
+	b.lastFailure = fetch
+	b.failures++
+	if b.failures >= BreakerThreshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// countsTowardBreaker reports whether a failure suggests the service itself
+// is unhealthy, as opposed to e.g. a 404 for an unknown aggregate
+func countsTowardBreaker(status int, err error) bool {
+	if status == 0 {
+		return err != nil && !errors.Is(err, ErrAuth)
+	}
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
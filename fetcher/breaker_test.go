@@ -0,0 +1,196 @@
+package fetcher
+
+import (
+	"context"
+	"drill/models"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// expire moves b's opening back past the cooldown
+func expire(b *breaker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openedAt = time.Now().Add(-BreakerCooldown - time.Second)
+}
+
+func TestBreakerOpensAfterThresholdFetches(t *testing.T) {
+	b := &breaker{}
+
+	// Several failed calls within one fetch count once
+	b.recordFailure(1)
+	b.recordFailure(1)
+	if !b.allow(2) {
+		t.Fatal("breaker opened after a single failed fetch")
+	}
+
+	b.recordFailure(2)
+	if b.allow(3) {
+		t.Fatalf("breaker still closed after %d failed fetches", BreakerThreshold)
+	}
+}
+
+func TestBreakerSuccessResetsCount(t *testing.T) {
+	b := &breaker{}
+	b.recordFailure(1)
+	b.recordSuccess()
+	b.recordFailure(2)
+	if !b.allow(3) {
+		t.Fatal("failures before a success counted towards the breaker")
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	open := func() *breaker {
+		b := &breaker{}
+		b.recordFailure(1)
+		b.recordFailure(2)
+		expire(b)
+		return b
+	}
+
+	t.Run("one probe at a time", func(t *testing.T) {
+		b := open()
+		if !b.allow(3) {
+			t.Fatal("no probe admitted after the cooldown")
+		}
+		if !b.allow(3) {
+			t.Fatal("probing fetch rejected on its second call")
+		}
+		if b.allow(4) {
+			t.Fatal("second probe admitted while the first is running")
+		}
+	})
+
+	t.Run("success closes", func(t *testing.T) {
+		b := open()
+		b.allow(3)
+		b.recordSuccess()
+		if !b.allow(4) || !b.allow(5) {
+			t.Fatal("breaker still open after a successful probe")
+		}
+	})
+
+	t.Run("failure reopens", func(t *testing.T) {
+		b := open()
+		b.allow(3)
+		b.recordFailure(3)
+		if b.allow(4) {
+			t.Fatal("breaker closed after a failed probe")
+		}
+	})
+
+	t.Run("release lets the next fetch probe", func(t *testing.T) {
+		b := open()
+		b.allow(3)
+		b.release(3)
+		if !b.allow(4) {
+			t.Fatal("released probe still blocks later fetches")
+		}
+		b.release(3) // a stale release must not free another fetch's probe
+		if b.allow(5) {
+			t.Fatal("release by another fetch freed the probe")
+		}
+	})
+}
+
+func TestBreakerSetKeysByServiceAndURL(t *testing.T) {
+	s := NewBreakerSet()
+	dev := models.ServiceConfig{Name: "orders", URL: "http://dev"}
+	prod := models.ServiceConfig{Name: "orders", URL: "http://prod"}
+	if s.forService(dev) == s.forService(prod) {
+		t.Fatal("environments share a breaker")
+	}
+	if s.forService(dev) != s.forService(dev) {
+		t.Fatal("same service got two breakers")
+	}
+}
+
+// openBreakerFetcher returns a fetcher for a service behind handler whose
+// breaker is open and past its cooldown
+func openBreakerFetcher(t *testing.T, handler http.HandlerFunc) (*Fetcher, models.ServiceConfig, *breaker) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	service := models.ServiceConfig{Name: "orders", URL: srv.URL}
+	f := NewFetcher([]models.ServiceConfig{service})
+	f.breakers = NewBreakerSet()
+	f.SetRetryPolicy(RetryPolicy{})
+
+	b := f.breakers.forService(service)
+	b.recordFailure(1)
+	b.recordFailure(2)
+	expire(b)
+	return f, service, b
+}
+
+func TestProbeReleasedWhenNotCounted(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		cancel bool
+	}{
+		{"not found", http.StatusNotFound, false},
+		{"bad request", http.StatusBadRequest, false},
+		{"cancelled", http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, service, b := openBreakerFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+			if _, err := f.get(ctx, service, service.URL, &FetchResult{}); err == nil {
+				t.Fatal("expected an error")
+			}
+			if !b.allow(f.id + 1) {
+				t.Fatal("probe not released, later fetches are rejected")
+			}
+		})
+	}
+}
+
+func TestProbeFailureReopens(t *testing.T) {
+	f, service, b := openBreakerFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	if _, err := f.get(context.Background(), service, service.URL, &FetchResult{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if b.allow(f.id + 1) {
+		t.Fatal("breaker closed after a failed probe")
+	}
+	if _, err := f.get(context.Background(), service, service.URL, &FetchResult{}); err != ErrCircuitOpen {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestOpenBreakerFailsFast(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	service := models.ServiceConfig{Name: "orders", URL: srv.URL}
+	breakers := NewBreakerSet()
+	for i := 0; i < BreakerThreshold+1; i++ {
+		f := NewFetcher([]models.ServiceConfig{service})
+		f.breakers = breakers
+		f.SetRetryPolicy(RetryPolicy{})
+		f.get(context.Background(), service, srv.URL, &FetchResult{})
+	}
+	if got := calls.Load(); got != BreakerThreshold {
+		t.Fatalf("service called %d times, want %d before the breaker opened", got, BreakerThreshold)
+	}
+}
@@ -0,0 +1,282 @@
+package fetcher
+
+import (
+	"bytes"
+	"drill/models"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decoder turns one page of a service's response into events or commands.
+// For cursor-paginated services it receives the items field of each page.
+type Decoder interface {
+	DecodeEvents(data []byte) ([]models.Event, error)
+	DecodeCommands(data []byte) ([]models.Command, error)
+}
+
+// DefaultDecoder is the decoder used when a service does not name one
+const DefaultDecoder = "json"
+
+// NewDecoder returns the decoder configured for a service
+func NewDecoder(cfg models.DecoderConfig) (Decoder, error) {
+	switch cfg.Type {
+	case "", DefaultDecoder:
+		return newJSONDecoder(cfg)
+	}
+	return nil, fmt.Errorf("unknown decoder %q, expected %s", cfg.Type, DefaultDecoder)
+}
+
+// Standard field names that a decoder's Fields may remap. Events read
+// their ID, alias and correlation fields from under "metadata" by default.
+var (
+	EventFields = map[string]string{
+		"eventId":       "metadata.eventId",
+		"eventAlias":    "metadata.eventAlias",
+		"persistedAt":   "metadata.persistedAt",
+		"correlationId": "metadata.correlationId",
+		"aggregateId":   "metadata.aggregateId",
+		"payload":       "payload",
+	}
+	CommandFields = map[string]string{
+		"commandId":     "commandId",
+		"commandStatus": "commandStatus",
+		"commandAlias":  "commandAlias",
+		"persistedAt":   "persistedAt",
+		"correlationId": "correlationId",
+		"aggregateId":   "aggregateId",
+		"payload":       "payload",
+	}
+)
+
+// jsonDecoder decodes JSON arrays, optionally found at a path inside the
+// response, with remapped field names and timestamp formats
+type jsonDecoder struct {
+	path       []string
+	events     map[string][]string
+	commands   map[string][]string
+	timeFormat string
+	plain      bool // standard layout, unmarshal directly
+}
+
+func newJSONDecoder(cfg models.DecoderConfig) (Decoder, error) {
+	d := &jsonDecoder{
+		path:       splitPath(cfg.Path),
+		events:     make(map[string][]string),
+		commands:   make(map[string][]string),
+		timeFormat: cfg.TimeFormat,
+		plain:      cfg.Path == "" && len(cfg.Fields) == 0 && (cfg.TimeFormat == "" || cfg.TimeFormat == "rfc3339"),
+	}
+
+	for name := range cfg.Fields {
+		_, isEvent := EventFields[name]
+		_, isCommand := CommandFields[name]
+		if !isEvent && !isCommand {
+			return nil, fmt.Errorf("unknown field %q in decoder mapping", name)
+		}
+	}
+	for name, def := range EventFields {
+		d.events[name] = splitPath(fieldOr(cfg.Fields, name, def))
+	}
+	for name, def := range CommandFields {
+		d.commands[name] = splitPath(fieldOr(cfg.Fields, name, def))
+	}
+
+	return d, nil
+}
+
+func fieldOr(fields map[string]string, name, def string) string {
+	if path, ok := fields[name]; ok && path != "" {
+		return path
+	}
+	return def
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+func (d *jsonDecoder) DecodeEvents(data []byte) ([]models.Event, error) {
+	if d.plain {
+		var events []models.Event
+		if err := json.Unmarshal(data, &events); err != nil {
+			return nil, err
+		}
+		return events, nil
+	}
+
+	items, err := d.items(data)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.Event, 0, len(items))
+	for i, item := range items {
+		var evt models.Event
+		evt.Metadata.EventID = stringAt(item, d.events["eventId"])
+		evt.Metadata.EventAlias = stringAt(item, d.events["eventAlias"])
+		evt.Metadata.CorrelationID = stringAt(item, d.events["correlationId"])
+		evt.Metadata.AggregateID = stringAt(item, d.events["aggregateId"])
+		evt.Payload = payloadAt(item, d.events["payload"])
+		if evt.Metadata.PersistedAt, err = d.timeAt(item, d.events["persistedAt"]); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		events = append(events, evt)
+	}
+	return events, nil
+}
+
+func (d *jsonDecoder) DecodeCommands(data []byte) ([]models.Command, error) {
+	if d.plain {
+		var commands []models.Command
+		if err := json.Unmarshal(data, &commands); err != nil {
+			return nil, err
+		}
+		return commands, nil
+	}
+
+	items, err := d.items(data)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([]models.Command, 0, len(items))
+	for i, item := range items {
+		var cmd models.Command
+		cmd.CommandID = stringAt(item, d.commands["commandId"])
+		cmd.CommandStatus = models.CommandStatus(stringAt(item, d.commands["commandStatus"]))
+		cmd.CommandAlias = stringAt(item, d.commands["commandAlias"])
+		cmd.CorrelationID = stringAt(item, d.commands["correlationId"])
+		cmd.AggregateID = stringAt(item, d.commands["aggregateId"])
+		cmd.Payload = payloadAt(item, d.commands["payload"])
+		if cmd.PersistedAt, err = d.timeAt(item, d.commands["persistedAt"]); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
+
+// items decodes the response and returns the array at the decoder's path
+func (d *jsonDecoder) items(data []byte) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	node, ok := lookup(doc, d.path)
+	if !ok {
+		return nil, fmt.Errorf("response has no %q field", strings.Join(d.path, "."))
+	}
+	items, ok := node.([]interface{})
+	if !ok {
+		if len(d.path) == 0 {
+			return nil, fmt.Errorf("response is not an array")
+		}
+		return nil, fmt.Errorf("%q is not an array", strings.Join(d.path, "."))
+	}
+	return items, nil
+}
+
+// lookup follows a dotted path through nested JSON objects
+func lookup(node interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// stringAt reads a string field, formatting numbers and booleans as text
+func stringAt(item interface{}, path []string) string {
+	v, ok := lookup(item, path)
+	if !ok || v == nil {
+		return ""
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// payloadAt reads the payload, which may be a JSON-encoded string or an
+// embedded object
+func payloadAt(item interface{}, path []string) string {
+	v, ok := lookup(item, path)
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func (d *jsonDecoder) timeAt(item interface{}, path []string) (time.Time, error) {
+	v, ok := lookup(item, path)
+	if !ok || v == nil {
+		return time.Time{}, nil
+	}
+	t, err := d.parseTime(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	}
+	return t, nil
+}
+
+// parseTime converts a timestamp in the decoder's format. Epoch formats
+// accept numbers or numeric strings.
+func (d *jsonDecoder) parseTime(v interface{}) (time.Time, error) {
+	var text string
+	switch v := v.(type) {
+	case string:
+		text = v
+	case json.Number:
+		text = v.String()
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp %v", v)
+	}
+
+	switch d.timeFormat {
+	case "", "rfc3339":
+		return time.Parse(time.RFC3339Nano, text)
+	case "unix", "unixmilli", "unixmicro", "unixnano":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || d.timeFormat != "unix" {
+				return time.Time{}, fmt.Errorf("invalid %s timestamp %q", d.timeFormat, text)
+			}
+			return time.Unix(0, int64(f*float64(time.Second))).UTC(), nil
+		}
+		switch d.timeFormat {
+		case "unix":
+			return time.Unix(n, 0).UTC(), nil
+		case "unixmilli":
+			return time.UnixMilli(n).UTC(), nil
+		case "unixmicro":
+			return time.UnixMicro(n).UTC(), nil
+		}
+		return time.Unix(0, n).UTC(), nil
+	}
+	return time.Parse(d.timeFormat, text)
+}
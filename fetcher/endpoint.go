@@ -0,0 +1,83 @@
+package fetcher
+
+import (
+	"drill/models"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Default endpoint templates, matching the original service convention
+const (
+	DefaultEventsPath   = "/events?{idType}={id}"
+	DefaultCommandsPath = "/commandLifecycle?{idType}={id}"
+)
+
+// placeholderRe matches {name} placeholders in endpoint templates
+var placeholderRe = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Placeholders lists the names allowed in endpoint templates
+var Placeholders = []string{"id", "idType"}
+
+// endpointURL builds the first request URL for one of a service's endpoints,
+// passing id under the given query key
+func endpointURL(service models.ServiceConfig, endpoint Endpoint, key models.IDType, id string) (string, error) {
+	tmpl := service.Endpoints.Events
+	if endpoint == EndpointCommands {
+		tmpl = service.Endpoints.Commands
+	}
+	if tmpl == "" {
+		tmpl = DefaultEventsPath
+		if endpoint == EndpointCommands {
+			tmpl = DefaultCommandsPath
+		}
+	}
+
+	values := map[string]string{"id": id, "idType": string(key)}
+
+	// Placeholders are escaped for the part of the URL they appear in
+	path, query, _ := strings.Cut(tmpl, "?")
+	path = expand(path, values, url.PathEscape)
+	query = expand(query, values, url.QueryEscape)
+
+	target := strings.TrimRight(service.URL, "/") + "/" + strings.TrimLeft(path, "/")
+	if query != "" {
+		target += "?" + query
+	}
+
+	if len(service.Endpoints.Query) == 0 {
+		return target, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s endpoint %q: %w", endpoint, tmpl, err)
+	}
+	q := u.Query()
+	for k, v := range service.Endpoints.Query {
+		q.Set(k, expand(v, values, func(s string) string { return s }))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// expand replaces known placeholders in s, escaping each value
+func expand(s string, values map[string]string, escape func(string) string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := values[m[1:len(m)-1]]; ok {
+			return escape(v)
+		}
+		return m
+	})
+}
+
+// TemplatePlaceholders returns the placeholder names used in an endpoint
+// template, for validation
+func TemplatePlaceholders(tmpl string) []string {
+	var names []string
+	for _, m := range placeholderRe.FindAllStringSubmatch(tmpl, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
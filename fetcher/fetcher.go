@@ -1,140 +1,454 @@
 package fetcher
 
 import (
+	"context"
 	"drill/models"
-	"encoding/json"
+	"drill/payload"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Endpoint identifies which of a service's endpoints a call went to
+type Endpoint string
+
+const (
+	EndpointEvents   Endpoint = "events"
+	EndpointCommands Endpoint = "commandLifecycle"
+)
+
+// FetchResult reports the outcome of a single service/endpoint call
 type FetchResult struct {
+	Service    string
+	Endpoint   Endpoint
+	ID         string // the ID the call looked up
+	StatusCode int    // 0 when no response was received
+	Attempts   int
+	Latency    time.Duration
+	Items      int
+	Pages      int
+	Error      error
+
+	Events   []models.Event
+	Commands []models.Command
+}
+
+// Failed reports whether the call returned an error
+func (r FetchResult) Failed() bool {
+	return r.Error != nil
+}
+
+// Summary returns a short description such as "payment-service events: 503"
+func (r FetchResult) Summary() string {
+	if r.Error == nil {
+		return fmt.Sprintf("%s %s: %d items", r.Service, r.Endpoint, r.Items)
+	}
+	if errors.Is(r.Error, ErrTruncated) {
+		return fmt.Sprintf("%s %s: %v (%d items kept)", r.Service, r.Endpoint, r.Error, r.Items)
+	}
+	retries := ""
+	if r.Attempts > 1 {
+		retries = fmt.Sprintf(" after %d attempts", r.Attempts)
+	}
+	var statusErr *StatusError
+	if errors.As(r.Error, &statusErr) && r.StatusCode == statusErr.StatusCode {
+		return fmt.Sprintf("%s %s: %d%s", r.Service, r.Endpoint, r.StatusCode, retries)
+	}
+	return fmt.Sprintf("%s %s: %v%s", r.Service, r.Endpoint, r.Error, retries)
+}
+
+// FetchReport holds the merged data from every service along with the
+// outcome of each individual call
+type FetchReport struct {
 	Events   []models.Event
 	Commands []models.Command
-	Error    error
+	Results  []FetchResult
+	Skipped  []SkippedService // services that cannot be queried for the ID
+
+	// Aggregates found by a correlation or command lookup, whose histories
+	// were loaded, and how many more were found beyond the cap
+	Aggregates        []string
+	DroppedAggregates int
+}
+
+// Failures returns the calls that did not succeed
+func (r *FetchReport) Failures() []FetchResult {
+	var failed []FetchResult
+	for _, res := range r.Results {
+		if res.Failed() {
+			failed = append(failed, res)
+		}
+	}
+	return failed
 }
 
+// StatusError is returned when a service responds with a non-200 status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// DefaultTimeout bounds each call to a service that sets no timeout of its own
+const DefaultTimeout = 30 * time.Second
+
 type Fetcher struct {
-	client   *http.Client
-	services []models.ServiceConfig
+	client    *http.Client
+	services  []models.ServiceConfig
+	onResult  func(FetchResult)
+	onPage    func(FetchResult)
+	onRelated func([]string)
+	retry     RetryPolicy
+	breakers  *BreakerSet
+	id        uint64 // identifies this fetcher's calls to the breakers
+
+	mu         sync.Mutex
+	tlsClients map[string]*http.Client
+	chains     map[string]*payload.Chain // payload decoding per service
 }
 
+// fetcherIDs numbers fetchers, so each counts once towards a breaker
+var fetcherIDs atomic.Uint64
+
+// NewFetcher returns a fetcher using DefaultRetryPolicy and the
+// session-wide SessionBreakers. Every call it makes, across lookups and
+// related aggregates, belongs to one fetch as far as the breakers are
+// concerned.
 func NewFetcher(services []models.ServiceConfig) *Fetcher {
 	return &Fetcher{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:   &http.Client{},
 		services: services,
+		retry:    DefaultRetryPolicy,
+		breakers: SessionBreakers,
+		id:       fetcherIDs.Add(1),
 	}
 }
 
-func (f *Fetcher) FetchAll(aggregateID string) ([]models.Event, []models.Command, error) {
+// SetRetryPolicy overrides the retry policy for transient failures
+func (f *Fetcher) SetRetryPolicy(p RetryPolicy) {
+	f.retry = p
+}
+
+// OnResult registers a callback invoked as each service/endpoint call
+// completes. Callbacks run sequentially on the FetchAll goroutine.
+func (f *Fetcher) OnResult(fn func(FetchResult)) {
+	f.onResult = fn
+}
+
+// OnPage registers a callback invoked after each page a call receives,
+// including the only page of an unpaginated call, with the running totals
+// so far and the events or commands decoded from that page. It may be
+// called from several goroutines at once, and always before the call's
+// OnResult.
+func (f *Fetcher) OnPage(fn func(FetchResult)) {
+	f.onPage = fn
+}
+
+// FetchAll queries every service for events and commands by aggregate ID
+func (f *Fetcher) FetchAll(ctx context.Context, aggregateID string) (*FetchReport, error) {
+	return f.FetchAllBy(ctx, models.LookupAggregate, aggregateID)
+}
+
+// FetchAllBy queries every service that supports the lookup kind, and whose
+// ID format accepts id, for events and commands concurrently, skipping the
+// rest. The returned report is always populated; the error is only set when
+// every call failed, no service could be queried, or ctx was cancelled.
+func (f *Fetcher) FetchAllBy(ctx context.Context, kind models.LookupKind, id string) (*FetchReport, error) {
 	var wg sync.WaitGroup
 	resultsChan := make(chan FetchResult, len(f.services)*2)
+	report := &FetchReport{}
 
 	for _, service := range f.services {
+		if reason := accepts(service, kind, id); reason != "" {
+			report.Skipped = append(report.Skipped, SkippedService{Service: service.Name, Reason: reason})
+			continue
+		}
+		key, _ := service.KeyFor(kind)
+
 		wg.Add(2)
 
 		// Fetch events
 		go func(svc models.ServiceConfig) {
 			defer wg.Done()
-			events, err := f.fetchEvents(svc, aggregateID)
-			resultsChan <- FetchResult{Events: events, Error: err}
+			resultsChan <- f.fetchEvents(ctx, svc, key, id)
 		}(service)
 
 		// Fetch commands
 		go func(svc models.ServiceConfig) {
 			defer wg.Done()
-			commands, err := f.fetchCommands(svc, aggregateID)
-			resultsChan <- FetchResult{Commands: commands, Error: err}
+			resultsChan <- f.fetchCommands(ctx, svc, key, id)
 		}(service)
 	}
 
+	if len(f.services) > 0 && len(report.Skipped) == len(f.services) {
+		return report, noServiceError(kind, id, report.Skipped)
+	}
+
 	go func() {
 		wg.Wait()
 		close(resultsChan)
 	}()
 
-	var allEvents []models.Event
-	var allCommands []models.Command
 	var errs []error
 
 	for result := range resultsChan {
-		if result.Error != nil {
-			errs = append(errs, result.Error)
-			continue
+		report.Results = append(report.Results, result)
+		if f.onResult != nil {
+			f.onResult(result)
 		}
-		allEvents = append(allEvents, result.Events...)
-		allCommands = append(allCommands, result.Commands...)
+		if result.Failed() {
+			errs = append(errs, errors.New(result.Summary()))
+		}
+		// Keep whatever pages arrived before a failure
+		report.Events = append(report.Events, result.Events...)
+		report.Commands = append(report.Commands, result.Commands...)
+	}
+
+	// Results arrive in completion order; keep them stable for display
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		if report.Results[i].Service != report.Results[j].Service {
+			return report.Results[i].Service < report.Results[j].Service
+		}
+		return report.Results[i].Endpoint > report.Results[j].Endpoint
+	})
+
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	if len(errs) > 0 && len(errs) == len(report.Results) {
+		return report, fmt.Errorf("all fetches failed:\n%w", errors.Join(errs...))
 	}
 
-	if len(errs) > 0 && len(allEvents) == 0 && len(allCommands) == 0 {
-		return nil, nil, fmt.Errorf("all fetches failed: %v", errs)
+	return report, nil
+}
+
+// timeoutFor returns the per-call timeout for a service
+func timeoutFor(service models.ServiceConfig) time.Duration {
+	if service.Timeout > 0 {
+		return service.Timeout
+	}
+	return DefaultTimeout
+}
+
+// ErrTimeout is returned when a call exceeds its service's timeout
+var ErrTimeout = errors.New("timed out")
+
+// response is a successful (or final failed) HTTP exchange
+type response struct {
+	body       []byte
+	header     http.Header
+	status     int
+	retryAfter time.Duration
+}
+
+// get performs a GET request, retrying transient failures according to the
+// fetcher's retry policy, and returns a 200 response. The status code and
+// attempt count are recorded on result.
+func (f *Fetcher) get(ctx context.Context, service models.ServiceConfig, target string, result *FetchResult) (response, error) {
+	breaker := f.breakers.forService(service)
+	if !breaker.allow(f.id) {
+		return response{}, ErrCircuitOpen
 	}
+	defer breaker.release(f.id)
+
+	for attempt := 0; ; attempt++ {
+		result.Attempts = attempt + 1
+
+		resp, err := f.attempt(ctx, service, target)
+		result.StatusCode = resp.status
+		if err == nil {
+			breaker.recordSuccess()
+			return resp, nil
+		}
+
+		if !isTransient(resp.status, err) || attempt >= f.retry.MaxRetries || ctx.Err() != nil {
+			if countsTowardBreaker(resp.status, err) && ctx.Err() == nil {
+				breaker.recordFailure(f.id)
+			}
+			return resp, err
+		}
 
-	return allEvents, allCommands, nil
+		delay := f.retry.backoff(attempt)
+		if resp.retryAfter > 0 {
+			delay = min(resp.retryAfter, f.retry.MaxDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return response{}, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
 }
 
-func (f *Fetcher) fetchEvents(service models.ServiceConfig, id string) ([]models.Event, error) {
-	url := fmt.Sprintf("%s/events?%s=%s", service.URL, service.IDType, id)
+// attempt performs a single GET request bounded by the service's timeout
+func (f *Fetcher) attempt(ctx context.Context, service models.ServiceConfig, target string) (response, error) {
+	timeout := timeoutFor(service)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return response{}, err
+	}
+	if err := authorize(req, service.Auth); err != nil {
+		return response{}, err
+	}
+
+	client, err := f.clientFor(service)
+	if err != nil {
+		return response{}, err
+	}
 
-	resp, err := f.client.Get(url)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch events from %s: %w", service.Name, err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return response{}, fmt.Errorf("%w after %s", ErrTimeout, timeout)
+		}
+		// Drop the method and URL so summaries stay short
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return response{}, urlErr.Err
+		}
+		return response{}, err
 	}
 	defer resp.Body.Close()
 
+	result := response{header: resp.Header, status: resp.StatusCode}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, service.Name)
+		result.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return result, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response from %s: %w", service.Name, err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return result, fmt.Errorf("%w after %s", ErrTimeout, timeout)
+		}
+		return result, fmt.Errorf("failed to read response: %w", err)
 	}
+	result.body = body
 
-	var events []models.Event
-	if err := json.Unmarshal(body, &events); err != nil {
-		return nil, fmt.Errorf("failed to parse events from %s: %w", service.Name, err)
-	}
+	return result, nil
+}
+
+func (f *Fetcher) fetchEvents(ctx context.Context, service models.ServiceConfig, key models.IDType, id string) (result FetchResult) {
+	result = FetchResult{Service: service.Name, Endpoint: EndpointEvents, ID: id}
+	start := time.Now()
+	defer func() { result.Latency = time.Since(start) }()
 
-	// Tag events with service name
-	for i := range events {
-		events[i].ServiceName = service.Name
+	reqURL, err := endpointURL(service, EndpointEvents, key, id)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	decoder, err := NewDecoder(service.Decoder)
+	if err != nil {
+		result.Error = err
+		return result
 	}
+	payloads, err := f.chainFor(service)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Error = f.fetchPages(ctx, service, reqURL, &result, func(items []byte) (int, error) {
+		events, err := decoder.DecodeEvents(items)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse events: %w", err)
+		}
+		payloads.DecodeEvents(events)
+
+		// Tag events with service name
+		for i := range events {
+			events[i].ServiceName = service.Name
+		}
+
+		result.Events = append(result.Events, events...)
+		return len(events), nil
+	})
 
-	return events, nil
+	return result
 }
 
-func (f *Fetcher) fetchCommands(service models.ServiceConfig, id string) ([]models.Command, error) {
-	url := fmt.Sprintf("%s/commandLifecycle?%s=%s", service.URL, service.IDType, id)
+// chainFor returns the payload decoding chain for service, built on first
+// use so that descriptors and schemas are read once per fetcher
+func (f *Fetcher) chainFor(service models.ServiceConfig) (*payload.Chain, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-	resp, err := f.client.Get(url)
+	if c, ok := f.chains[service.Name]; ok {
+		return c, nil
+	}
+	c, err := payload.NewChain(service.Payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch commands from %s: %w", service.Name, err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, service.Name)
+	if f.chains == nil {
+		f.chains = make(map[string]*payload.Chain)
 	}
+	f.chains[service.Name] = c
+	return c, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (f *Fetcher) fetchCommands(ctx context.Context, service models.ServiceConfig, key models.IDType, id string) (result FetchResult) {
+	result = FetchResult{Service: service.Name, Endpoint: EndpointCommands, ID: id}
+	start := time.Now()
+	defer func() { result.Latency = time.Since(start) }()
+
+	reqURL, err := endpointURL(service, EndpointCommands, key, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response from %s: %w", service.Name, err)
+		result.Error = err
+		return result
 	}
-
-	var commands []models.Command
-	if err := json.Unmarshal(body, &commands); err != nil {
-		return nil, fmt.Errorf("failed to parse commands from %s: %w", service.Name, err)
+	decoder, err := NewDecoder(service.Decoder)
+	if err != nil {
+		result.Error = err
+		return result
 	}
-
-	// Tag commands with service name
-	for i := range commands {
-		commands[i].ServiceName = service.Name
+	payloads, err := f.chainFor(service)
+	if err != nil {
+		result.Error = err
+		return result
 	}
 
-	return commands, nil
+	result.Error = f.fetchPages(ctx, service, reqURL, &result, func(items []byte) (int, error) {
+		commands, err := decoder.DecodeCommands(items)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse commands: %w", err)
+		}
+		payloads.DecodeCommands(commands)
+
+		// Tag commands with service name
+		for i := range commands {
+			commands[i].ServiceName = service.Name
+		}
+
+		result.Commands = append(result.Commands, commands...)
+		return len(commands), nil
+	})
+
+	return result
+}
+
+// SupportedServices returns the services that can be queried by a lookup kind
+func SupportedServices(services []models.ServiceConfig, kind models.LookupKind) []models.ServiceConfig {
+	var supported []models.ServiceConfig
+	for _, svc := range services {
+		if _, ok := svc.KeyFor(kind); ok {
+			supported = append(supported, svc)
+		}
+	}
+	return supported
 }
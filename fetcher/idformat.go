@@ -0,0 +1,124 @@
+package fetcher
+
+import (
+	"drill/models"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ulidRe matches a 26 character Crockford base32 ULID
+var ulidRe = regexp.MustCompile(`^(?i)[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+// CompileIDPattern compiles an idPattern to match whole IDs, for
+// IDFormat.Regexp
+func CompileIDPattern(pattern string) (*regexp.Regexp, error) {
+	// Checked on its own first, so a pattern such as a)|(b cannot escape
+	// the anchors
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+// ValidateID checks an aggregate ID against a service's format rule
+func ValidateID(format models.IDFormat, id string) error {
+	switch format.Type {
+	case "", models.IDFormatUUID:
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("not a valid UUID")
+		}
+	case models.IDFormatULID:
+		if !ulidRe.MatchString(id) {
+			return fmt.Errorf("not a valid ULID")
+		}
+	case models.IDFormatRegex:
+		if format.Regexp == nil {
+			return fmt.Errorf("pattern %q was not compiled", format.Pattern)
+		}
+		if !format.Regexp.MatchString(id) {
+			return fmt.Errorf("does not match %s", format.Pattern)
+		}
+	case models.IDFormatNone:
+	default:
+		return fmt.Errorf("unknown id format %q", format.Type)
+	}
+	return nil
+}
+
+// accepts reports why a service cannot be queried for id by kind, or ""
+// when it can. Only aggregate IDs are subject to format rules.
+func accepts(service models.ServiceConfig, kind models.LookupKind, id string) string {
+	if _, ok := service.KeyFor(kind); !ok {
+		return fmt.Sprintf("does not support %s lookups", kind)
+	}
+	if kind == models.LookupAggregate {
+		if err := ValidateID(service.IDFormat, id); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+// QueryableServices returns the services FetchAllBy will query for id
+func QueryableServices(services []models.ServiceConfig, kind models.LookupKind, id string) []models.ServiceConfig {
+	var queryable []models.ServiceConfig
+	for _, svc := range services {
+		if accepts(svc, kind, id) == "" {
+			queryable = append(queryable, svc)
+		}
+	}
+	return queryable
+}
+
+// CheckID returns an error unless at least one service can be queried for
+// id by kind. The error lists each rule the ID failed.
+func CheckID(services []models.ServiceConfig, kind models.LookupKind, id string) error {
+	if id == "" {
+		return fmt.Errorf("ID cannot be empty")
+	}
+	if len(services) == 0 {
+		return nil
+	}
+
+	var skipped []SkippedService
+	for _, svc := range services {
+		reason := accepts(svc, kind, id)
+		if reason == "" {
+			return nil
+		}
+		skipped = append(skipped, SkippedService{Service: svc.Name, Reason: reason})
+	}
+	return noServiceError(kind, id, skipped)
+}
+
+// SkippedService is a service left out of a lookup, and why
+type SkippedService struct {
+	Service string
+	Reason  string
+}
+
+// noServiceError explains why no service could be queried, grouping
+// services that rejected the ID for the same reason
+func noServiceError(kind models.LookupKind, id string, skipped []SkippedService) error {
+	var reasons []string
+	byReason := make(map[string][]string)
+	for _, s := range skipped {
+		if _, ok := byReason[s.Reason]; !ok {
+			reasons = append(reasons, s.Reason)
+		}
+		byReason[s.Reason] = append(byReason[s.Reason], s.Service)
+	}
+
+	if len(reasons) == 1 && strings.HasPrefix(reasons[0], "does not support") {
+		return fmt.Errorf("no configured service supports %s lookups", kind)
+	}
+
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%s (%s)", reason, strings.Join(byReason[reason], ", "))
+	}
+	return fmt.Errorf("%q is not a valid %s ID for any service: %s", id, kind, strings.Join(parts, "; "))
+}
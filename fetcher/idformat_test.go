@@ -0,0 +1,51 @@
+package fetcher
+
+import (
+	"drill/models"
+	"testing"
+)
+
+func TestValidateID(t *testing.T) {
+	re, err := CompileIDPattern(`acct_[0-9]+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regex := models.IDFormat{Type: models.IDFormatRegex, Pattern: `acct_[0-9]+`, Regexp: re}
+
+	tests := []struct {
+		name   string
+		format models.IDFormat
+		id     string
+		ok     bool
+	}{
+		{"uuid by default", models.IDFormat{}, "3f2c1a9e-8b4d-4c6e-9f1a-2b3c4d5e6f70", true},
+		{"not a uuid", models.IDFormat{}, "acct_1", false},
+		{"ulid", models.IDFormat{Type: models.IDFormatULID}, "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"not a ulid", models.IDFormat{Type: models.IDFormatULID}, "01ARZ3NDEKTSV4RRFFQ69G5FAU0", false},
+		{"regex", regex, "acct_12", true},
+		{"regex matches the whole ID", regex, "x_acct_12", false},
+		{"regex prefix only", regex, "acct_12x", false},
+		{"regex not compiled", models.IDFormat{Type: models.IDFormatRegex, Pattern: `acct_[0-9]+`}, "acct_12", false},
+		{"none", models.IDFormat{Type: models.IDFormatNone}, "anything", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateID(tt.format, tt.id); (err == nil) != tt.ok {
+				t.Errorf("ValidateID(%q) = %v, want ok %v", tt.id, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCompileIDPattern(t *testing.T) {
+	if _, err := CompileIDPattern(`a)|(b`); err == nil {
+		t.Error("a pattern that would escape the anchors should not compile")
+	}
+	re, err := CompileIDPattern(`a|b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.MatchString("ab") || !re.MatchString("b") {
+		t.Errorf("%s should match a or b alone", re)
+	}
+}
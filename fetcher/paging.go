@@ -0,0 +1,194 @@
+package fetcher
+
+import (
+	"context"
+	"drill/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrTruncated is returned when a paginated call stops before the last page
+var ErrTruncated = errors.New("truncated")
+
+// Pagination defaults, used when a service enables paging without setting
+// the corresponding option
+const (
+	DefaultPageSizeParam = "limit"
+	DefaultCursorParam   = "cursor"
+	DefaultCursorField   = "nextCursor"
+	DefaultItemsField    = "items"
+	DefaultMaxPages      = 1000
+)
+
+func pagingWithDefaults(p models.PaginationConfig) models.PaginationConfig {
+	if p.PageSizeParam == "" {
+		p.PageSizeParam = DefaultPageSizeParam
+	}
+	if p.CursorParam == "" {
+		p.CursorParam = DefaultCursorParam
+	}
+	if p.CursorField == "" {
+		p.CursorField = DefaultCursorField
+	}
+	if p.ItemsField == "" {
+		p.ItemsField = DefaultItemsField
+	}
+	if p.MaxPages <= 0 {
+		p.MaxPages = DefaultMaxPages
+	}
+	return p
+}
+
+// fetchPages requests firstURL and follows the service's pagination settings,
+// passing each page's JSON item array to handle, which returns how many items
+// it decoded. Pages handled before an error are kept on result.
+func (f *Fetcher) fetchPages(ctx context.Context, service models.ServiceConfig, firstURL string, result *FetchResult, handle func(items []byte) (int, error)) error {
+	paging := pagingWithDefaults(service.Pagination)
+
+	next := firstURL
+	if paging.Mode != models.PaginationNone && paging.PageSize > 0 {
+		var err error
+		next, err = setQueryParam(firstURL, paging.PageSizeParam, strconv.Itoa(paging.PageSize))
+		if err != nil {
+			return err
+		}
+	}
+
+	seen := make(map[string]bool)
+
+	for next != "" {
+		if result.Pages >= paging.MaxPages {
+			return fmt.Errorf("%w after %d pages", ErrTruncated, paging.MaxPages)
+		}
+		if seen[next] {
+			return fmt.Errorf("%w: pagination returned a page twice", ErrTruncated)
+		}
+		seen[next] = true
+
+		resp, err := f.get(ctx, service, next, result)
+		if err != nil {
+			if result.Pages > 0 {
+				return fmt.Errorf("page %d: %w", result.Pages+1, err)
+			}
+			return err
+		}
+
+		items := resp.body
+		current := next
+		next = ""
+
+		switch paging.Mode {
+		case models.PaginationLink:
+			next, err = nextLink(resp.header.Get("Link"), current)
+			if err != nil {
+				return err
+			}
+
+		case models.PaginationCursor:
+			var page map[string]json.RawMessage
+			if err := json.Unmarshal(resp.body, &page); err != nil {
+				return fmt.Errorf("failed to parse page: %w", err)
+			}
+			items = page[paging.ItemsField]
+			if items == nil {
+				return fmt.Errorf("page has no %q field", paging.ItemsField)
+			}
+			if cursor := cursorValue(page[paging.CursorField]); cursor != "" {
+				next, err = setQueryParam(current, paging.CursorParam, cursor)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		n, err := handle(items)
+		if err != nil {
+			return err
+		}
+		result.Items += n
+		result.Pages++
+
+		if f.onPage != nil {
+			f.onPage(result.lastPage(n))
+		}
+	}
+
+	return nil
+}
+
+// lastPage returns the running totals with only the events or commands of
+// the latest page, the last n decoded
+func (r FetchResult) lastPage(n int) FetchResult {
+	switch r.Endpoint {
+	case EndpointEvents:
+		r.Events = r.Events[len(r.Events)-n:]
+	case EndpointCommands:
+		r.Commands = r.Commands[len(r.Commands)-n:]
+	}
+	return r
+}
+
+// cursorValue reads a cursor given as a JSON string or number. Null, missing
+// and empty cursors mean there are no more pages.
+func cursorValue(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+func setQueryParam(rawURL, key, value string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// nextLink extracts the rel="next" target from an RFC 8288 Link header,
+// resolved against the URL of the current page
+func nextLink(header, current string) (string, error) {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(key) != "rel" {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				if rel != "next" {
+					continue
+				}
+				base, err := url.Parse(current)
+				if err != nil {
+					return "", err
+				}
+				ref, err := url.Parse(strings.Trim(target, "<>"))
+				if err != nil {
+					return "", fmt.Errorf("invalid Link header: %w", err)
+				}
+				return base.ResolveReference(ref).String(), nil
+			}
+		}
+	}
+	return "", nil
+}
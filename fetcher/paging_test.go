@@ -0,0 +1,119 @@
+package fetcher
+
+import (
+	"context"
+	"drill/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// pagedServer serves n events two to a page under cursor pagination, and
+// an empty page of commands
+func pagedServer(t *testing.T, n int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			fmt.Fprint(w, `{"items":[]}`)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := min(start+2, n)
+		fmt.Fprint(w, `{"items":[`)
+		for i := start; i < end; i++ {
+			if i > start {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"metadata":{"eventId":"e%d","eventAlias":"Changed","persistedAt":"2024-01-01T00:00:0%dZ"},"payload":"{}"}`, i, i)
+		}
+		fmt.Fprint(w, `]`)
+		if end < n {
+			fmt.Fprintf(w, `,"nextCursor":"%d"`, end)
+		}
+		fmt.Fprint(w, `}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOnPageReportsEachPage(t *testing.T) {
+	srv := pagedServer(t, 5)
+	service := models.ServiceConfig{
+		Name:       "orders",
+		IDType:     models.IDTypeAggregate,
+		URL:        srv.URL,
+		Pagination: models.PaginationConfig{Mode: models.PaginationCursor},
+	}
+	f := NewFetcher([]models.ServiceConfig{service})
+	f.breakers = NewBreakerSet()
+
+	var mu sync.Mutex
+	var pages []FetchResult
+	f.OnPage(func(r FetchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Endpoint == EndpointEvents {
+			pages = append(pages, r)
+		}
+	})
+
+	report, err := f.FetchAll(context.Background(), "3f2c1a9e-8b4d-4c6e-9f1a-2b3c4d5e6f70")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("got %d event pages, want 3", len(pages))
+	}
+
+	var streamed []string
+	for i, page := range pages {
+		if page.Pages != i+1 {
+			t.Errorf("page %d: Pages = %d", i+1, page.Pages)
+		}
+		if want := min(2*(i+1), 5); page.Items != want {
+			t.Errorf("page %d: Items = %d, want %d", i+1, page.Items, want)
+		}
+		for _, e := range page.Events {
+			if e.ServiceName != "orders" {
+				t.Errorf("event %s not tagged with its service", e.Metadata.EventID)
+			}
+			streamed = append(streamed, e.Metadata.EventID)
+		}
+	}
+	if fmt.Sprint(streamed) != "[e0 e1 e2 e3 e4]" {
+		t.Errorf("streamed %v, want each event once in order", streamed)
+	}
+	if len(report.Events) != 5 {
+		t.Errorf("report has %d events, want 5", len(report.Events))
+	}
+}
+
+func TestOnPageUnpaginated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"metadata":{"eventId":"e0","eventAlias":"Changed"},"payload":"{}"}]`)
+	}))
+	t.Cleanup(srv.Close)
+	f := NewFetcher([]models.ServiceConfig{{Name: "orders", IDType: models.IDTypeAggregate, URL: srv.URL}})
+	f.breakers = NewBreakerSet()
+
+	var mu sync.Mutex
+	pages := make(map[Endpoint]int)
+	f.OnPage(func(r FetchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		pages[r.Endpoint]++
+		if r.Endpoint == EndpointEvents && len(r.Events) != 1 {
+			t.Errorf("page has %d events, want 1", len(r.Events))
+		}
+	})
+
+	if _, err := f.FetchAll(context.Background(), "3f2c1a9e-8b4d-4c6e-9f1a-2b3c4d5e6f70"); err != nil {
+		t.Fatal(err)
+	}
+	if pages[EndpointEvents] != 1 || pages[EndpointCommands] != 1 {
+		t.Errorf("pages = %v, want one per endpoint", pages)
+	}
+}
@@ -0,0 +1,114 @@
+package fetcher
+
+import (
+	"context"
+	"drill/models"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxRelatedAggregates caps how many aggregates a correlation or command
+// lookup expands into
+const MaxRelatedAggregates = 25
+
+// OnRelated registers a callback invoked by FetchRelated once the aggregates
+// behind a correlation or command ID are known, before their histories are
+// fetched
+func (f *Fetcher) OnRelated(fn func(aggregates []string)) {
+	f.onRelated = fn
+}
+
+// FetchRelated looks up id by kind and, for correlation and command IDs,
+// then loads the full history of every aggregate the matches belong to.
+// Aggregate lookups are the same as FetchAllBy.
+func (f *Fetcher) FetchRelated(ctx context.Context, kind models.LookupKind, id string) (*FetchReport, error) {
+	report, err := f.FetchAllBy(ctx, kind, id)
+	if err != nil || kind == models.LookupAggregate {
+		return report, err
+	}
+
+	report.Aggregates = relatedAggregates(report)
+	if len(report.Aggregates) == 0 {
+		return report, fmt.Errorf("no aggregates found for %s %s", kind, id)
+	}
+	if len(report.Aggregates) > MaxRelatedAggregates {
+		report.DroppedAggregates = len(report.Aggregates) - MaxRelatedAggregates
+		report.Aggregates = report.Aggregates[:MaxRelatedAggregates]
+	}
+
+	if f.onRelated != nil {
+		f.onRelated(report.Aggregates)
+	}
+
+	events := make(map[string]bool)
+	for _, e := range report.Events {
+		events[e.ServiceName+"/"+e.Metadata.EventID] = true
+	}
+	commands := make(map[string]bool)
+	for _, c := range report.Commands {
+		commands[c.ServiceName+"/"+c.CommandID] = true
+	}
+
+	for _, aggregateID := range report.Aggregates {
+		// Failed calls are kept in Results; an aggregate that could not be
+		// loaded at all still leaves what the first lookup found
+		history, _ := f.FetchAllBy(ctx, models.LookupAggregate, aggregateID)
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		report.Results = append(report.Results, history.Results...)
+		for _, e := range history.Events {
+			key := e.ServiceName + "/" + e.Metadata.EventID
+			if e.Metadata.EventID != "" && events[key] {
+				continue
+			}
+			events[key] = true
+			report.Events = append(report.Events, e)
+		}
+		for _, c := range history.Commands {
+			key := c.ServiceName + "/" + c.CommandID
+			if c.CommandID != "" && commands[key] {
+				continue
+			}
+			commands[key] = true
+			report.Commands = append(report.Commands, c)
+		}
+	}
+
+	return report, nil
+}
+
+// relatedAggregates returns the distinct aggregate IDs in a report, ordered
+// by when each was first touched and then by ID, so a lookup keeps the same
+// aggregates whenever the list is capped
+func relatedAggregates(report *FetchReport) []string {
+	first := make(map[string]time.Time)
+	note := func(id string, at time.Time) {
+		if id == "" {
+			return
+		}
+		if t, ok := first[id]; !ok || at.Before(t) {
+			first[id] = at
+		}
+	}
+	for _, c := range report.Commands {
+		note(c.AggregateID, c.PersistedAt)
+	}
+	for _, e := range report.Events {
+		note(e.Metadata.AggregateID, e.Metadata.PersistedAt)
+	}
+
+	ids := make([]string, 0, len(first))
+	for id := range first {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if a, b := first[ids[i]], first[ids[j]]; !a.Equal(b) {
+			return a.Before(b)
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
@@ -0,0 +1,73 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how transient failures are retried
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt; 0 disables
+	BaseDelay  time.Duration // delay before the first retry, doubled each time
+	MaxDelay   time.Duration // cap on any single delay, including Retry-After
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  250 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+}
+
+// backoff returns the delay before retry number attempt+1: exponential
+// growth with equal jitter, so concurrent calls don't retry in lockstep
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// isTransient reports whether a failed call is worth retrying. Timeouts are
+// not retried: a hung service would otherwise hold the fetch for several
+// timeouts in a row.
+func isTransient(status int, err error) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	if status != 0 {
+		return false
+	}
+	if errors.Is(err, ErrTimeout) || errors.Is(err, ErrAuth) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	// Connection refused/reset and other transport errors
+	return true
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date. It returns zero when absent or unparseable.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,169 @@
+package fetcher
+
+import (
+	"context"
+	"drill/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{70, time.Second}, // the shift overflows
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			d := p.backoff(tt.attempt)
+			if d < tt.max/2 || d >= tt.max {
+				t.Fatalf("backoff(%d) = %s, want in [%s, %s)", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+
+	if d := (RetryPolicy{}).backoff(0); d != 0 {
+		t.Errorf("zero policy backoff = %s, want 0", d)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{http.StatusTooManyRequests, nil, true},
+		{http.StatusBadGateway, nil, true},
+		{http.StatusServiceUnavailable, nil, true},
+		{http.StatusGatewayTimeout, nil, true},
+		{http.StatusInternalServerError, nil, false},
+		{http.StatusNotFound, nil, false},
+		{0, errors.New("connection refused"), true},
+		{0, fmt.Errorf("%w after 1s", ErrTimeout), false},
+		{0, fmt.Errorf("%w: bad token", ErrAuth), false},
+		{0, context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.status, tt.err); got != tt.want {
+			t.Errorf("isTransient(%d, %v) = %v, want %v", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("3"); d != 3*time.Second {
+		t.Errorf("seconds: got %s", d)
+	}
+	at := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(at); d <= 8*time.Second || d > 10*time.Second {
+		t.Errorf("date: got %s", d)
+	}
+	for _, v := range []string{"", "0", "-1", "soon", "Mon, 01 Jan 2001 00:00:00 GMT"} {
+		if d := parseRetryAfter(v); d != 0 {
+			t.Errorf("parseRetryAfter(%q) = %s, want 0", v, d)
+		}
+	}
+}
+
+// flakyServer fails the first failures calls with status, then succeeds
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func retryFetcher(srv *httptest.Server, p RetryPolicy) (*Fetcher, models.ServiceConfig) {
+	service := models.ServiceConfig{Name: "orders", URL: srv.URL}
+	f := NewFetcher([]models.ServiceConfig{service})
+	f.breakers = NewBreakerSet()
+	f.SetRetryPolicy(p)
+	return f, service
+}
+
+func TestGetRetries(t *testing.T) {
+	p := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	t.Run("recovers", func(t *testing.T) {
+		srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable, nil)
+		f, service := retryFetcher(srv, p)
+		var result FetchResult
+		if _, err := f.get(context.Background(), service, srv.URL, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Attempts != 3 || calls.Load() != 3 {
+			t.Errorf("attempts = %d, calls = %d, want 3", result.Attempts, calls.Load())
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		srv, calls := flakyServer(t, 5, http.StatusServiceUnavailable, nil)
+		f, service := retryFetcher(srv, p)
+		var result FetchResult
+		_, err := f.get(context.Background(), service, srv.URL, &result)
+		var status *StatusError
+		if !errors.As(err, &status) || status.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("err = %v, want a 503 StatusError", err)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("calls = %d, want 3", calls.Load())
+		}
+	})
+
+	t.Run("permanent failures are not retried", func(t *testing.T) {
+		srv, calls := flakyServer(t, 5, http.StatusInternalServerError, nil)
+		f, service := retryFetcher(srv, p)
+		f.get(context.Background(), service, srv.URL, &FetchResult{})
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+	})
+
+	t.Run("retry-after is capped", func(t *testing.T) {
+		srv, _ := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}})
+		f, service := retryFetcher(srv, p)
+		start := time.Now()
+		if _, err := f.get(context.Background(), service, srv.URL, &FetchResult{}); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("waited %s, want at most MaxDelay", elapsed)
+		}
+	})
+
+	t.Run("cancel stops waiting", func(t *testing.T) {
+		srv, calls := flakyServer(t, 5, http.StatusServiceUnavailable, nil)
+		f, service := retryFetcher(srv, RetryPolicy{MaxRetries: 5, BaseDelay: time.Minute, MaxDelay: time.Minute})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := f.get(ctx, service, srv.URL, &FetchResult{}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want the context's error", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type node interface {
+	eval(s *subject) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(s *subject) bool { return n.left.eval(s) && n.right.eval(s) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(s *subject) bool { return n.left.eval(s) || n.right.eval(s) }
+
+type notNode struct{ x node }
+
+func (n notNode) eval(s *subject) bool { return !n.x.eval(s) }
+
+// comparison is a single field op value test
+type comparison struct {
+	field string   // lowercased field name
+	path  []string // payload path, when field is payload and a path is given
+	op    string
+	value string
+	num   float64 // value as a number, when isNum
+	isNum bool
+	time  time.Time // value as a time, for persistedAt
+}
+
+func (c comparison) eval(s *subject) bool {
+	switch {
+	case c.field == "persistedat":
+		return compareOrder(c.op, s.persistedAt.Compare(c.time))
+	case c.field == "payload" && len(c.path) > 0:
+		v, ok := s.lookup(c.path)
+		if !ok {
+			return false
+		}
+		if n, isNum := v.(json.Number); isNum && c.isNum {
+			if f, err := n.Float64(); err == nil && c.op != "~" && c.op != "!~" {
+				return compareOrder(c.op, compareFloat(f, c.num))
+			}
+		}
+		return c.compareString(scalarString(v))
+	}
+	return c.compareString(s.field(c.field))
+}
+
+// compareString applies the operator to a string value. Equality and ~ are
+// case-insensitive; ordering is numeric when both sides are numbers.
+func (c comparison) compareString(v string) bool {
+	switch c.op {
+	case "=":
+		return strings.EqualFold(v, c.value)
+	case "!=":
+		return !strings.EqualFold(v, c.value)
+	case "~":
+		return strings.Contains(strings.ToLower(v), strings.ToLower(c.value))
+	case "!~":
+		return !strings.Contains(strings.ToLower(v), strings.ToLower(c.value))
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && c.isNum {
+		return compareOrder(c.op, compareFloat(f, c.num))
+	}
+	return compareOrder(c.op, strings.Compare(v, c.value))
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareOrder turns a three-way comparison result into the operator's answer
+func compareOrder(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// lookup walks a path into the payload, decoding it on first use
+func (s *subject) lookup(path []string) (interface{}, bool) {
+	if !s.parsed {
+		s.parsed = true
+		dec := json.NewDecoder(strings.NewReader(s.payload))
+		dec.UseNumber()
+		if err := dec.Decode(&s.payloadDoc); err != nil {
+			s.payloadDoc = nil
+		}
+	}
+
+	cur := s.payloadDoc
+	for _, key := range path {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// scalarString renders a decoded JSON value for string comparison; objects
+// and arrays compare as their JSON encoding
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,123 @@
+// Package filter parses and evaluates filter expressions over events and
+// commands, such as
+//
+//	service=payment-service AND alias~Refund AND persistedAt>2024-01-15T10:00
+//	type=command AND (status=COMMAND_FAILED OR payload.amount>=50)
+//
+// Comparisons are field op value, where op is one of = != ~ !~ > >= < <=
+// (~ is a case-insensitive substring match). Values containing spaces or
+// operators are written in double quotes. Comparisons combine with AND, OR,
+// NOT and parentheses; AND binds tighter than OR.
+//
+// Fields are type (command or event), id, alias, service, status,
+// correlationId, aggregateId, persistedAt and payload. payload.<path> reads
+// a value inside the JSON payload, with numeric indexes for arrays
+// (payload.items.0.sku). A comparison against a missing payload path is
+// false.
+package filter
+
+import (
+	"drill/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Filter is a parsed expression
+type Filter struct {
+	expr node
+	text string
+}
+
+// String returns the expression as written
+func (f *Filter) String() string {
+	return f.text
+}
+
+// MatchEvent reports whether an event satisfies the filter
+func (f *Filter) MatchEvent(e models.Event) bool {
+	return f.expr.eval(&subject{
+		kind:          "event",
+		id:            e.Metadata.EventID,
+		alias:         e.Metadata.EventAlias,
+		service:       e.ServiceName,
+		correlationID: e.Metadata.CorrelationID,
+		aggregateID:   e.Metadata.AggregateID,
+		persistedAt:   e.Metadata.PersistedAt,
+		payload:       e.Payload,
+	})
+}
+
+// MatchCommand reports whether a command satisfies the filter
+func (f *Filter) MatchCommand(c models.Command) bool {
+	return f.expr.eval(&subject{
+		kind:          "command",
+		id:            c.CommandID,
+		alias:         c.CommandAlias,
+		service:       c.ServiceName,
+		status:        string(c.CommandStatus),
+		correlationID: c.CorrelationID,
+		aggregateID:   c.AggregateID,
+		persistedAt:   c.PersistedAt,
+		payload:       c.Payload,
+	})
+}
+
+// Fields lists the field names a comparison may use, besides payload.<path>
+var Fields = []string{"type", "id", "alias", "service", "status", "correlationId", "aggregateId", "persistedAt", "payload"}
+
+// subject is the common view of an event or command that expressions are
+// evaluated against
+type subject struct {
+	kind          string
+	id            string
+	alias         string
+	service       string
+	status        string
+	correlationID string
+	aggregateID   string
+	persistedAt   time.Time
+	payload       string
+
+	parsed     bool
+	payloadDoc interface{}
+}
+
+// field returns the value of a plain field by lowercased name
+func (s *subject) field(name string) string {
+	switch name {
+	case "type":
+		return s.kind
+	case "id":
+		return s.id
+	case "alias":
+		return s.alias
+	case "service":
+		return s.service
+	case "status":
+		return s.status
+	case "correlationid":
+		return s.correlationID
+	case "aggregateid":
+		return s.aggregateID
+	case "payload":
+		return s.payload
+	}
+	return ""
+}
+
+// ParseError reports where an expression could not be parsed
+type ParseError struct {
+	Pos     int // byte offset into the expression
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("filter: position %d: %s", e.Pos+1, e.Message)
+}
+
+// LooksLikeExpression reports whether text is meant as a filter expression
+// rather than free text, because it contains a comparison operator
+func LooksLikeExpression(text string) bool {
+	return strings.ContainsAny(text, "=~<>")
+}
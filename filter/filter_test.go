@@ -0,0 +1,182 @@
+package filter
+
+import (
+	"drill/models"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testEvent = models.Event{
+		Metadata: models.EventMetadata{
+			EventID:       "e-1",
+			EventAlias:    "RefundIssued",
+			PersistedAt:   time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			CorrelationID: "c-1",
+			AggregateID:   "a-1",
+		},
+		Payload:     `{"amount":"75","total":120,"note":"a = b AND c","quote":"say \"hi\"","items":[{"sku":"S-1","qty":2}],"nested":{"deep":{"flag":true}},"empty":null}`,
+		ServiceName: "payment-service",
+	}
+	testCommand = models.Command{
+		CommandID:     "cmd-1",
+		CommandStatus: models.CommandFailed,
+		CommandAlias:  "PlaceOrder",
+		PersistedAt:   time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+		Payload:       `{"amount":9,"code":"010"}`,
+		CorrelationID: "c-1",
+		AggregateID:   "a-1",
+		ServiceName:   "order-service",
+	}
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr           string
+		event, command bool
+	}{
+		// fields
+		{`type=event`, true, false},
+		{`type=command AND status=COMMAND_FAILED`, false, true},
+		{`service=PAYMENT-SERVICE`, true, false},
+		{`alias~refund`, true, false},
+		{`alias!~refund`, false, true},
+		{`id!=e-1`, false, true},
+		{`correlationId=c-1 AND aggregateId=A-1`, true, true},
+		{`payload~S-1`, true, false},
+
+		// precedence: AND binds tighter than OR, NOT tighter than both
+		{`type=event OR service=none AND alias=PlaceOrder`, true, false},
+		{`(type=event OR service=none) AND alias=PlaceOrder`, false, false},
+		{`NOT type=event AND alias=PlaceOrder`, false, true},
+		{`NOT (type=event AND alias=PlaceOrder)`, true, true},
+		{`NOT (type=event OR alias=PlaceOrder)`, false, false},
+		{`NOT NOT type=event`, true, false},
+		{`type=event or type=command`, true, true},
+
+		// quoted values
+		{`payload.note="a = b AND c"`, true, false},
+		{`payload.note~"= b AND"`, true, false},
+		{`payload.quote="say \"hi\""`, true, false},
+		{`alias="Refund Issued"`, false, false},
+
+		// numbers compare as numbers, anything else as strings
+		{`payload.total>99`, true, false},
+		{`payload.total=120.0`, true, false},
+		{`payload.amount>8`, true, true},
+		{`payload.code>9`, false, true},
+		{`payload.code=10`, false, false},
+		{`payload.code=010`, false, true},
+		{`alias>Q`, true, false},
+
+		// persistedAt layouts, read as UTC without a zone
+		{`persistedAt>2024-01-15T10:00`, true, false},
+		{`persistedAt>=2024-01-15`, true, true},
+		{`persistedAt<"2024-01-15 09:30"`, false, true},
+		{`persistedAt="2024-01-15 10:30:00"`, true, false},
+		{`persistedAt=2024-01-15T10:30:00`, true, false},
+		{`persistedAt=2024-01-15T10:30:00Z`, true, false},
+		{`persistedAt<=2024-01-15T10:00:00+01:00`, false, true},
+		{`persistedAt<2024-01-15T10:00:00+01:00`, false, false},
+		{`persistedAt!=2024-01-15T09:00:00.000Z`, true, false},
+
+		// payload paths
+		{`payload.items.0.sku=S-1`, true, false},
+		{`payload.items[0].qty>=2`, true, false},
+		{`PAYLOAD.nested.deep.flag=true`, true, false},
+		{`payload.empty=null`, true, false},
+		{`payload.items~S-1`, true, false},
+		{`payload.missing=x`, false, false},
+		{`payload.missing!=x`, false, false},
+		{`NOT payload.missing=x`, true, true},
+		{`payload.items.5.sku~S`, false, false},
+		{`payload.items.x.sku~S`, false, false},
+		{`payload.total.x=1`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.MatchEvent(testEvent); got != tt.event {
+				t.Errorf("MatchEvent = %v, want %v", got, tt.event)
+			}
+			if got := f.MatchCommand(testCommand); got != tt.command {
+				t.Errorf("MatchCommand = %v, want %v", got, tt.command)
+			}
+		})
+	}
+}
+
+func TestMatchInvalidPayload(t *testing.T) {
+	f, err := Parse(`payload.a=1 OR payload~"not json"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.MatchEvent(models.Event{Payload: "not json"}) {
+		t.Error("a payload that is not JSON should still match as text")
+	}
+	if f.MatchEvent(models.Event{Payload: "plain"}) {
+		t.Error("a path into a payload that is not JSON should not match")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		want string
+	}{
+		{``, 0, "empty filter"},
+		{`   `, 0, "empty filter"},
+		{`alias="abc`, 6, "unterminated string"},
+		{`alias!abc`, 5, "expected != or !~"},
+		{`nope=1`, 0, `unknown field "nope"`},
+		{`=a`, 0, "expected a field name"},
+		{`alias abc`, 6, "expected an operator after alias"},
+		{`alias=`, 6, "expected a value after ="},
+		{`alias=(a)`, 6, "expected a value after ="},
+		{`(alias=a`, 8, "expected )"},
+		{`alias=a service=b`, 8, `unexpected "service", expected AND or OR`},
+		{`alias=a AND`, 11, "expected a field name"},
+		{`NOT`, 3, "expected a field name"},
+		{`persistedAt~2024`, 11, "persistedAt does not support ~"},
+		{`persistedAt>yesterday`, 12, `invalid time "yesterday"`},
+		{`persistedAt=2024-01-15 10:30`, 23, `unexpected "10:30"`},
+		{`payload..a=1`, 0, `invalid payload path "payload..a"`},
+		{`payload.a.=1`, 0, `invalid payload path "payload.a."`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("err = %v, want a ParseError", err)
+			}
+			if perr.Pos != tt.pos || !strings.Contains(perr.Message, tt.want) {
+				t.Errorf("err at %d: %s, want at %d: %s", perr.Pos, perr.Message, tt.pos, tt.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeExpression(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"refund", false},
+		{"payment-service failed", false},
+		{"alias=Refund", true},
+		{"payload.amount>50", true},
+		{"alias~ref", true},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeExpression(tt.text); got != tt.want {
+			t.Errorf("LooksLikeExpression(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators, longest first so "!=" is not read as "!" then "="
+var operators = []string{">=", "<=", "!=", "!~", "=", "~", ">", "<"}
+
+func lex(text string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(text) {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(text) && text[i] != '"' {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				sb.WriteByte(text[i])
+				i++
+			}
+			if i >= len(text) {
+				return nil, &ParseError{Pos: start, Message: "unterminated string"}
+			}
+			i++
+			toks = append(toks, token{tokString, sb.String(), start})
+		default:
+			if op := operatorAt(text, i); op != "" {
+				toks = append(toks, token{tokOp, op, i})
+				i += len(op)
+				continue
+			}
+			if c == '!' {
+				return nil, &ParseError{Pos: i, Message: "expected != or !~"}
+			}
+			start := i
+			for i < len(text) && !strings.ContainsRune(" \t\n()\"!=~<>", rune(text[i])) {
+				i++
+			}
+			toks = append(toks, token{tokWord, text[start:i], start})
+		}
+	}
+	return append(toks, token{tokEOF, "", len(text)}), nil
+}
+
+func operatorAt(text string, i int) string {
+	for _, op := range operators {
+		if strings.HasPrefix(text[i:], op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// Parse compiles a filter expression
+func Parse(text string) (*Filter, error) {
+	toks, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, &ParseError{Pos: 0, Message: "empty filter"}
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &ParseError{Pos: t.pos, Message: fmt.Sprintf("unexpected %q, expected AND or OR", t.text)}
+	}
+	return &Filter{expr: expr, text: text}, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword, consuming it
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.keyword("NOT") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+
+	if t := p.peek(); t.kind == tokLParen {
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, &ParseError{Pos: t.pos, Message: "expected )"}
+		}
+		return x, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	ft := p.next()
+	if ft.kind != tokWord {
+		return nil, &ParseError{Pos: ft.pos, Message: "expected a field name"}
+	}
+	cmp, err := newComparison(ft)
+	if err != nil {
+		return nil, err
+	}
+
+	ot := p.next()
+	if ot.kind != tokOp {
+		return nil, &ParseError{Pos: ot.pos, Message: fmt.Sprintf("expected an operator after %s", ft.text)}
+	}
+	cmp.op = ot.text
+
+	vt := p.next()
+	if vt.kind != tokWord && vt.kind != tokString {
+		return nil, &ParseError{Pos: vt.pos, Message: fmt.Sprintf("expected a value after %s", ot.text)}
+	}
+	cmp.value = vt.text
+	if n, err := strconv.ParseFloat(vt.text, 64); err == nil {
+		cmp.num, cmp.isNum = n, true
+	}
+
+	if cmp.field == "persistedat" {
+		if cmp.op == "~" || cmp.op == "!~" {
+			return nil, &ParseError{Pos: ot.pos, Message: "persistedAt does not support ~"}
+		}
+		t, err := parseTime(vt.text)
+		if err != nil {
+			return nil, &ParseError{Pos: vt.pos, Message: err.Error()}
+		}
+		cmp.time = t
+	}
+
+	return cmp, nil
+}
+
+// newComparison validates a field name, splitting payload paths
+func newComparison(ft token) (comparison, error) {
+	name := ft.text
+	if rest, ok := cutPrefixFold(name, "payload."); ok {
+		path := strings.Split(strings.NewReplacer("[", ".", "]", "").Replace(rest), ".")
+		for _, part := range path {
+			if part == "" {
+				return comparison{}, &ParseError{Pos: ft.pos, Message: fmt.Sprintf("invalid payload path %q", name)}
+			}
+		}
+		return comparison{field: "payload", path: path}, nil
+	}
+
+	for _, f := range Fields {
+		if strings.EqualFold(name, f) {
+			return comparison{field: strings.ToLower(f)}, nil
+		}
+	}
+	return comparison{}, &ParseError{Pos: ft.pos, Message: fmt.Sprintf("unknown field %q, expected one of %s or payload.<path>", name, strings.Join(Fields, ", "))}
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+// timeLayouts are accepted for persistedAt values; those without a zone are
+// read as UTC
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected e.g. 2024-01-15T10:00 or 2024-01-15", s)
+}
@@ -1,95 +1,75 @@
 package main
 
 import (
-	"bufio"
-	"drill/models"
+	"drill/cli"
+	"drill/config"
 	"drill/ui"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	// Parse services from CSV file
-	services := parseServicesFromFile()
-
-	// Create the entry screen model
-	model := ui.NewEntryModel(services)
-
-	// Create and run program
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error running program: %v\n", err)
+	// Load services from .drill.yaml (or the deprecated .drill.csv)
+	cfg, err := config.Load()
+	if err != nil && !errors.Is(err, config.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-}
-
-func parseServicesFromFile() []models.ServiceConfig {
-	// Look for .drill.csv in current directory, then home directory
-	paths := []string{
-		".drill.csv",
-		filepath.Join(os.Getenv("HOME"), ".drill.csv"),
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
 	}
 
-	var file *os.File
-
-	for _, path := range paths {
-		f, err := os.Open(path)
-		if err == nil {
-			file = f
-			break
+	env, args, err := envFlag(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if env != "" {
+		if err := cfg.UseEnvironment(env); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
 		}
 	}
 
-	if file == nil {
-		return nil
+	// Headless mode
+	if len(args) > 0 && args[0] == "fetch" {
+		os.Exit(cli.RunFetch(args[1:], cfg))
 	}
-	defer file.Close()
-
-	var services []models.ServiceConfig
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Split by comma: name,idType,url
-		parts := strings.Split(line, ",")
-		if len(parts) != 3 {
-			fmt.Fprintf(os.Stderr, "Warning: line %d invalid format '%s', expected 'name,idType,url'\n", lineNum, line)
-			continue
-		}
+	// Create the entry screen model
+	model := ui.NewEntryModel(cfg)
 
-		name := strings.TrimSpace(parts[0])
-		idTypeStr := strings.TrimSpace(parts[1])
-		url := strings.TrimSpace(parts[2])
+	// Create and run program
+	p := tea.NewProgram(model, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Printf("Error running program: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-		var idType models.IDType
-		switch strings.ToLower(idTypeStr) {
-		case "indexid":
-			idType = models.IDTypeIndex
-		case "aggregateid":
-			idType = models.IDTypeAggregate
+// envFlag removes --env <name> (or --env=<name>) from args, wherever it
+// appears, so it works for both the TUI and subcommands
+func envFlag(args []string) (string, []string, error) {
+	var env string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--env" || arg == "-env":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			env = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--env="), strings.HasPrefix(arg, "-env="):
+			env = arg[strings.Index(arg, "=")+1:]
 		default:
-			fmt.Fprintf(os.Stderr, "Warning: line %d invalid idType '%s', using aggregateId\n", lineNum, idTypeStr)
-			idType = models.IDTypeAggregate
+			rest = append(rest, arg)
 		}
-
-		services = append(services, models.ServiceConfig{
-			Name:   name,
-			IDType: idType,
-			URL:    url,
-		})
 	}
-
-	return services
+	return env, rest, nil
 }
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -23,6 +25,10 @@ type Event struct {
 	Metadata    EventMetadata `json:"metadata"`
 	Payload     string        `json:"payload"`
 	ServiceName string        `json:"-"` // Added to track which service this came from
+
+	RawPayload      string `json:"rawPayload,omitempty"`      // the stored payload, when Payload was decoded from it
+	PayloadDecoding string `json:"payloadDecoding,omitempty"` // how Payload was decoded from the stored form
+	PayloadError    string `json:"payloadError,omitempty"`    // why the stored payload could not be decoded
 }
 
 type Command struct {
@@ -34,17 +40,186 @@ type Command struct {
 	CorrelationID string        `json:"correlationId"`
 	AggregateID   string        `json:"aggregateId"`
 	ServiceName   string        `json:"-"` // Added to track which service this came from
+
+	RawPayload      string `json:"rawPayload,omitempty"`      // the stored payload, when Payload was decoded from it
+	PayloadDecoding string `json:"payloadDecoding,omitempty"` // how Payload was decoded from the stored form
+	PayloadError    string `json:"payloadError,omitempty"`    // why the stored payload could not be decoded
 }
 
 type IDType string
 
 const (
-	IDTypeAggregate IDType = "aggregateId"
-	IDTypeIndex     IDType = "indexId"
+	IDTypeAggregate   IDType = "aggregateId"
+	IDTypeIndex       IDType = "indexId"
+	IDTypeCorrelation IDType = "correlationId"
+	IDTypeCommand     IDType = "commandId"
+)
+
+// Kind returns the kind of lookup a query key answers. aggregateId and
+// indexId are both aggregate lookups under different parameter names.
+func (t IDType) Kind() LookupKind {
+	switch t {
+	case IDTypeCorrelation:
+		return LookupCorrelation
+	case IDTypeCommand:
+		return LookupCommand
+	}
+	return LookupAggregate
+}
+
+// LookupKind is the kind of ID an investigation starts from
+type LookupKind string
+
+const (
+	LookupAggregate   LookupKind = "aggregate"
+	LookupCorrelation LookupKind = "correlation"
+	LookupCommand     LookupKind = "command"
+)
+
+// LookupKinds lists every kind in the order they are offered
+var LookupKinds = []LookupKind{LookupAggregate, LookupCorrelation, LookupCommand}
+
+// Label returns a display name such as "Correlation ID"
+func (k LookupKind) Label() string {
+	switch k {
+	case LookupCorrelation:
+		return "Correlation ID"
+	case LookupCommand:
+		return "Command ID"
+	}
+	return "Aggregate ID"
+}
+
+type AuthType string
+
+const (
+	AuthNone   AuthType = ""
+	AuthBearer AuthType = "bearer"
+	AuthBasic  AuthType = "basic"
 )
 
+// SecretRef is a secret value given as "env:NAME" (read from the
+// environment), "cmd:command" (stdout of a shell command) or plain text
+type SecretRef string
+
+// IsReference reports whether the secret is resolved from the environment
+// or a command rather than written inline
+func (r SecretRef) IsReference() bool {
+	s := string(r)
+	return strings.HasPrefix(s, "env:") || strings.HasPrefix(s, "cmd:")
+}
+
+type AuthConfig struct {
+	Type     AuthType
+	Token    SecretRef // bearer
+	Username SecretRef // basic
+	Password SecretRef // basic
+	Headers  map[string]SecretRef
+
+	// mTLS, as PEM file paths
+	ClientCert string
+	ClientKey  string
+	CACert     string
+}
+
+type PaginationMode string
+
+const (
+	PaginationNone   PaginationMode = ""
+	PaginationCursor PaginationMode = "cursor"
+	PaginationLink   PaginationMode = "link"
+)
+
+// PaginationConfig describes how a service pages long result sets. Unset
+// fields fall back to the fetcher's defaults.
+type PaginationConfig struct {
+	Mode          PaginationMode
+	PageSize      int
+	PageSizeParam string // query parameter carrying the page size
+	CursorParam   string // cursor mode: query parameter carrying the cursor
+	CursorField   string // cursor mode: response field holding the next cursor
+	ItemsField    string // cursor mode: response field holding the items
+	MaxPages      int
+}
+
+// EndpointsConfig overrides where a service's events and commands are
+// fetched from. Paths are relative to the service URL and may use the {id}
+// and {idType} placeholders in the path or query string. Empty paths use
+// the original /events?<idType>=<id> and /commandLifecycle?<idType>=<id>.
+type EndpointsConfig struct {
+	Events   string
+	Commands string
+	Query    map[string]string // static query parameters added to both
+}
+
+// IDFormatType names a rule that aggregate IDs must follow
+type IDFormatType string
+
+const (
+	IDFormatUUID  IDFormatType = "uuid"
+	IDFormatULID  IDFormatType = "ulid"
+	IDFormatRegex IDFormatType = "regex"
+	IDFormatNone  IDFormatType = "none"
+)
+
+// IDFormat is the rule a service's aggregate IDs must follow. The zero value
+// requires a UUID.
+type IDFormat struct {
+	Type    IDFormatType
+	Pattern string         // regex: the whole ID must match
+	Regexp  *regexp.Regexp // regex: Pattern compiled once when the config is loaded
+}
+
+// DecoderConfig selects and configures the decoder for a service's
+// responses. The zero value decodes the standard JSON layout.
+type DecoderConfig struct {
+	Type       string            // decoder name, empty for "json"
+	Path       string            // dotted path to the item array, e.g. "data"
+	Fields     map[string]string // standard field name to dotted source path
+	TimeFormat string            // rfc3339, unix, unixmilli, unixmicro, unixnano or a Go layout
+}
+
+// ReducerConfig selects how an event's payload is folded into the
+// reconstructed state of its aggregate
+type ReducerConfig struct {
+	Type string // reducer name, empty for "merge"
+	Path string // dotted path in the state to apply at, empty for the root
+}
+
+// PayloadDecoderConfig is one step of the chain that decodes a service's
+// stored payloads. File paths are resolved against the configuration file.
+type PayloadDecoderConfig struct {
+	Type       string            // base64, json, protobuf or avro
+	Descriptor string            // protobuf: FileDescriptorSet from protoc --descriptor_set_out
+	Message    string            // protobuf: fully qualified message type
+	Schema     string            // avro: .avsc schema file
+	Aliases    map[string]string // event or command alias to its own message type (protobuf) or schema file (avro)
+}
+
 type ServiceConfig struct {
-	Name   string
-	IDType IDType
-	URL    string
+	Name       string
+	IDType     IDType   // query key for aggregate lookups, empty if unsupported
+	IDTypes    []IDType // further query keys, e.g. correlationId
+	URL        string
+	Timeout    time.Duration // per-call timeout, zero uses the fetcher default
+	Auth       AuthConfig
+	Pagination PaginationConfig
+	Endpoints  EndpointsConfig
+	Decoder    DecoderConfig
+	IDFormat   IDFormat
+	Payload    []PayloadDecoderConfig // empty decodes base64 JSON and nested JSON
+}
+
+// KeyFor returns the query key the service uses for a kind of lookup, and
+// false when the service cannot be queried that way
+func (s ServiceConfig) KeyFor(kind LookupKind) (IDType, bool) {
+	if kind == LookupAggregate && s.IDType != "" {
+		return s.IDType, true
+	}
+	for _, t := range s.IDTypes {
+		if t.Kind() == kind {
+			return t, true
+		}
+	}
+	return "", false
 }
@@ -0,0 +1,144 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Decoded payloads are built from these values so that object keys keep
+// their order: object, []interface{}, string, json.Number, bool and nil.
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+// object is a JSON object with keys in document or schema order
+type object []member
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(encodeJSON(m.key))
+		buf.WriteByte(':')
+		buf.Write(encodeJSON(m.value))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeJSON writes v compactly, without escaping HTML characters
+func encodeJSON(v interface{}) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return []byte("null")
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// parseJSON reads a single JSON document, keeping key order
+func parseJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := parseValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("trailing data")
+	}
+	return v, nil
+}
+
+func parseValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	if delim == '[' {
+		arr := []interface{}{}
+		for dec.More() {
+			v, err := parseValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+
+	obj := object{}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		v, err := parseValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		obj = append(obj, member{key: key.(string), value: v})
+	}
+	_, err = dec.Token()
+	return obj, err
+}
+
+// maxNesting bounds how many times JSON inside strings is unescaped
+const maxNesting = 10
+
+// unnest replaces strings that hold a JSON object or array with the parsed
+// value, recursively, and reports whether anything changed. A top-level
+// string may also hold another encoded string.
+func unnest(v interface{}, depth int) (interface{}, bool) {
+	switch val := v.(type) {
+	case string:
+		if depth >= maxNesting {
+			return v, false
+		}
+		text := strings.TrimSpace(val)
+		if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") && (depth > 0 || !strings.HasPrefix(text, `"`)) {
+			return v, false
+		}
+		inner, err := parseJSON([]byte(text))
+		if err != nil {
+			return v, false
+		}
+		inner, _ = unnest(inner, depth+1)
+		return inner, true
+	case object:
+		changed := false
+		for i, m := range val {
+			var c bool
+			if val[i].value, c = unnest(m.value, max(depth, 1)); c {
+				changed = true
+			}
+		}
+		return val, changed
+	case []interface{}:
+		changed := false
+		for i, item := range val {
+			var c bool
+			if val[i], c = unnest(item, max(depth, 1)); c {
+				changed = true
+			}
+		}
+		return val, changed
+	}
+	return v, false
+}
@@ -0,0 +1,182 @@
+// Package payload decodes stored event and command payloads into readable
+// JSON. A service configures a chain of steps, such as base64 followed by
+// protobuf; services without one get base64 detection and nested JSON
+// unescaping.
+package payload
+
+import (
+	"bytes"
+	"drill/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Step is one stage of a decoding chain. It returns the decoded data with a
+// short description of what it did, or the data unchanged and an empty
+// description when it does not apply. A step returns ErrSkip for payloads
+// it is not set up to decode.
+type Step interface {
+	Decode(data []byte, alias string) ([]byte, string, error)
+}
+
+// ErrSkip is returned by a step for aliases it has no type for. The chain
+// then leaves the payload as stored, since the earlier steps only prepared
+// it for this one.
+var ErrSkip = errors.New("alias not decoded by this step")
+
+// NewStep returns the step configured by cfg
+func NewStep(cfg models.PayloadDecoderConfig) (Step, error) {
+	switch cfg.Type {
+	case "base64":
+		return newBase64Step(cfg)
+	case "json":
+		return newJSONStep(cfg)
+	case "protobuf":
+		return newProtobufStep(cfg)
+	case "avro":
+		return newAvroStep(cfg)
+	}
+	return nil, fmt.Errorf("unknown payload decoder %q, expected one of: avro, base64, json, protobuf", cfg.Type)
+}
+
+// Chain runs a service's decoding steps in order
+type Chain struct {
+	steps []Step
+}
+
+// NewChain builds the chain for a service. Without configured steps it
+// decodes base64 that holds JSON and unescapes nested JSON.
+func NewChain(cfgs []models.PayloadDecoderConfig) (*Chain, error) {
+	if len(cfgs) == 0 {
+		return &Chain{steps: []Step{base64Step{jsonOnly: true}, jsonStep{}}}, nil
+	}
+
+	c := &Chain{}
+	for i, cfg := range cfgs {
+		s, err := NewStep(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("payload decoder %d: %w", i+1, err)
+		}
+		c.steps = append(c.steps, s)
+	}
+	return c, nil
+}
+
+// Decode runs payload through the chain. It returns the decoded payload
+// and the steps that changed it, e.g. "base64 → protobuf acme.Entry". On
+// error, or when a step skips the alias, the payload is returned as stored.
+func (c *Chain) Decode(payload, alias string) (decoded, via string, err error) {
+	data := []byte(payload)
+	var done []string
+	for _, s := range c.steps {
+		out, desc, err := s.Decode(data, alias)
+		if errors.Is(err, ErrSkip) {
+			return payload, "", nil
+		}
+		if err != nil {
+			return payload, "", err
+		}
+		if desc != "" {
+			data = out
+			done = append(done, desc)
+		}
+	}
+	return string(data), strings.Join(done, " → "), nil
+}
+
+// DecodeEvents decodes every event's payload in place, keeping the stored
+// form in RawPayload and recording how it was decoded or why it could not be
+func (c *Chain) DecodeEvents(events []models.Event) {
+	for i := range events {
+		e := &events[i]
+		stored := e.Payload
+		var err error
+		if e.Payload, e.PayloadDecoding, err = c.Decode(stored, e.Metadata.EventAlias); err != nil {
+			e.PayloadError = err.Error()
+		}
+		if e.PayloadDecoding != "" {
+			e.RawPayload = stored
+		}
+	}
+}
+
+// DecodeCommands decodes every command's payload in place, keeping the
+// stored form in RawPayload and recording how it was decoded or why it
+// could not be
+func (c *Chain) DecodeCommands(commands []models.Command) {
+	for i := range commands {
+		cmd := &commands[i]
+		stored := cmd.Payload
+		var err error
+		if cmd.Payload, cmd.PayloadDecoding, err = c.Decode(stored, cmd.CommandAlias); err != nil {
+			cmd.PayloadError = err.Error()
+		}
+		if cmd.PayloadDecoding != "" {
+			cmd.RawPayload = stored
+		}
+	}
+}
+
+// noSettings rejects settings that a step does not use
+func noSettings(cfg models.PayloadDecoderConfig) error {
+	if cfg.Descriptor != "" || cfg.Message != "" || cfg.Schema != "" || len(cfg.Aliases) > 0 {
+		return fmt.Errorf("%s takes no settings", cfg.Type)
+	}
+	return nil
+}
+
+// minBase64 is the shortest text treated as base64, so that short words
+// made of base64 letters are left alone
+const minBase64 = 8
+
+// base64Step decodes standard or URL-safe base64. Text that is not valid
+// base64 passes through.
+type base64Step struct {
+	jsonOnly bool // keep the result only if it is JSON
+}
+
+func newBase64Step(cfg models.PayloadDecoderConfig) (Step, error) {
+	return base64Step{}, noSettings(cfg)
+}
+
+func (s base64Step) Decode(data []byte, alias string) ([]byte, string, error) {
+	text := string(bytes.TrimSpace(data))
+	if len(text) < minBase64 || len(text)%4 != 0 {
+		return data, "", nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+		out, err := enc.Strict().DecodeString(text)
+		if err != nil {
+			continue
+		}
+		if s.jsonOnly && !json.Valid(out) {
+			return data, "", nil
+		}
+		return out, "base64", nil
+	}
+	return data, "", nil
+}
+
+// jsonStep unescapes JSON held in strings: a payload that is a JSON string
+// of a document, and string values inside the payload that hold objects or
+// arrays
+type jsonStep struct{}
+
+func newJSONStep(cfg models.PayloadDecoderConfig) (Step, error) {
+	return jsonStep{}, noSettings(cfg)
+}
+
+func (jsonStep) Decode(data []byte, alias string) ([]byte, string, error) {
+	v, err := parseJSON(data)
+	if err != nil {
+		return data, "", nil
+	}
+	v, changed := unnest(v, 0)
+	if !changed {
+		return data, "", nil
+	}
+	return encodeJSON(v), "nested JSON", nil
+}
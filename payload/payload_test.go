@@ -0,0 +1,248 @@
+package payload
+
+import (
+	"drill/models"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The fixtures in testdata are generated from their sources there:
+//
+//	protoc --proto_path=testdata --descriptor_set_out=testdata/order.desc order.proto
+//	protoc --proto_path=testdata --encode=acme.Order order.proto < testdata/order.txtpb > testdata/order.bin
+//
+// and used.bin is used.json in Avro binary under used.avsc, e.g. with
+// goavro's NativeFromTextual and BinaryFromNative.
+
+const (
+	orderJSON = `{"orderId":"o-1","amount":"1500","status":"PAID","tags":["a","b"],"counts":{"x":2},"line":{"sku":"S","qty":3},"delta":-2,"price":9.5,"blob":"AAE="}`
+	usedJSON  = `{"at":1704067200000,"attrs":{"a":1,"k":7},"id":"u1","kind":"B","n":42,"note":"hi","score":1.5,"tags":["x"]}`
+)
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newTestChain(t *testing.T, cfgs ...models.PayloadDecoderConfig) *Chain {
+	t.Helper()
+	c, err := NewChain(cfgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func protobufConfig() models.PayloadDecoderConfig {
+	return models.PayloadDecoderConfig{
+		Type:       "protobuf",
+		Descriptor: filepath.Join("testdata", "order.desc"),
+		Aliases:    map[string]string{"OrderPlaced": "acme.Order"},
+	}
+}
+
+func avroConfig() models.PayloadDecoderConfig {
+	return models.PayloadDecoderConfig{
+		Type:    "avro",
+		Aliases: map[string]string{"Used": filepath.Join("testdata", "used.avsc")},
+	}
+}
+
+func TestDefaultChain(t *testing.T) {
+	c := newTestChain(t)
+	tests := []struct {
+		name, payload, want, via string
+	}{
+		{"plain JSON", `{"a":1}`, `{"a":1}`, ""},
+		{"nested JSON", `{"a":"{\"b\":[1,2]}"}`, `{"a":{"b":[1,2]}}`, "nested JSON"},
+		{"JSON string", `"{\"a\":1}"`, `{"a":1}`, "nested JSON"},
+		{"base64 JSON", base64.StdEncoding.EncodeToString([]byte(`{"a":"{\"b\":2}"}`)), `{"a":{"b":2}}`, "base64 → nested JSON"},
+		{"base64 of non-JSON", base64.StdEncoding.EncodeToString([]byte("not json")), base64.StdEncoding.EncodeToString([]byte("not json")), ""},
+		{"short word", "abcd", "abcd", ""},
+		{"text", "hello there", "hello there", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, via, err := c.Decode(tt.payload, "Any")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || via != tt.via {
+				t.Errorf("Decode(%q) = %q, %q, want %q, %q", tt.payload, got, via, tt.want, tt.via)
+			}
+		})
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	msg := fixture(t, "order.bin")
+	c := newTestChain(t, models.PayloadDecoderConfig{Type: "base64"}, protobufConfig())
+
+	got, via, err := c.Decode(base64.StdEncoding.EncodeToString(msg), "OrderPlaced")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != orderJSON {
+		t.Errorf("got %s\nwant %s", got, orderJSON)
+	}
+	if via != "base64 → protobuf acme.Order" {
+		t.Errorf("via = %q", via)
+	}
+}
+
+func TestProtobufMalformed(t *testing.T) {
+	msg := fixture(t, "order.bin")
+	step, err := NewStep(protobufConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"truncated", msg[:len(msg)-1], "cannot parse"},
+		{"truncated key", append(append([]byte{}, msg...), 0x80), "cannot parse"},
+		{"field number 0", []byte{0x00, 0x01}, "cannot parse"},
+		{"bad nested message", []byte{0x32, 0x02, 0x0a, 0x05}, "cannot parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := step.Decode(tt.data, "OrderPlaced")
+			// The protobuf runtime varies the spacing of its messages
+			// between builds, so match the words on their own
+			if err == nil || !strings.HasPrefix(err.Error(), "protobuf acme.Order: ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtobufConfig(t *testing.T) {
+	bad := filepath.Join(t.TempDir(), "bad.desc")
+	if err := os.WriteFile(bad, []byte{0x0a, 0x10, 0x01}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  models.PayloadDecoderConfig
+		want string
+	}{
+		{"unknown message", models.PayloadDecoderConfig{Type: "protobuf", Descriptor: filepath.Join("testdata", "order.desc"), Message: "acme.Missing"}, "not found"},
+		{"truncated descriptor", models.PayloadDecoderConfig{Type: "protobuf", Descriptor: bad, Message: "acme.Order"}, "invalid descriptor set"},
+		{"no descriptor", models.PayloadDecoderConfig{Type: "protobuf", Message: "acme.Order"}, "requires a descriptor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStep(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvro(t *testing.T) {
+	datum := fixture(t, "used.bin")
+	c := newTestChain(t, models.PayloadDecoderConfig{Type: "base64"}, avroConfig())
+
+	got, via, err := c.Decode(base64.StdEncoding.EncodeToString(datum), "Used")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != usedJSON {
+		t.Errorf("got %s\nwant %s", got, usedJSON)
+	}
+	if via != "base64 → avro acme.Used" {
+		t.Errorf("via = %q", via)
+	}
+}
+
+func TestAvroMalformed(t *testing.T) {
+	datum := fixture(t, "used.bin")
+	step, err := NewStep(avroConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badEnum := append([]byte{}, datum...)
+	badEnum[4] = 0x06 // kind 3, past the last symbol
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"truncated", datum[:len(datum)-3], `field "at": short buffer`},
+		{"empty", nil, `field "id"`},
+		{"trailing bytes", append(append([]byte{}, datum...), 0x00), "1 bytes left over"},
+		{"bad enum", badEnum, "read index: 3"},
+		{"negative string length", []byte{0x03}, "negative size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := step.Decode(tt.data, "Used")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmappedAliasPassesThrough(t *testing.T) {
+	chains := map[string]*Chain{
+		"protobuf": newTestChain(t, models.PayloadDecoderConfig{Type: "base64"}, protobufConfig()),
+		"avro":     newTestChain(t, models.PayloadDecoderConfig{Type: "base64"}, avroConfig()),
+	}
+	stored := base64.StdEncoding.EncodeToString([]byte{0x08, 0x01, 0x02, 0x03})
+	for name, c := range chains {
+		t.Run(name, func(t *testing.T) {
+			got, via, err := c.Decode(stored, "Unmapped")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != stored || via != "" {
+				t.Errorf("Decode = %q, %q, want the stored payload unchanged", got, via)
+			}
+		})
+	}
+
+	step, err := NewStep(protobufConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := step.Decode([]byte{0x08}, "Unmapped"); !errors.Is(err, ErrSkip) {
+		t.Errorf("err = %v, want ErrSkip", err)
+	}
+}
+
+func TestDecodeEventsKeepsStoredPayload(t *testing.T) {
+	c := newTestChain(t, models.PayloadDecoderConfig{Type: "base64"}, avroConfig())
+	stored := base64.StdEncoding.EncodeToString(fixture(t, "used.bin"))
+	events := []models.Event{
+		{Metadata: models.EventMetadata{EventAlias: "Used"}, Payload: stored},
+		{Metadata: models.EventMetadata{EventAlias: "Used"}, Payload: "AAAA"},
+		{Metadata: models.EventMetadata{EventAlias: "Other"}, Payload: stored},
+	}
+	c.DecodeEvents(events)
+
+	if e := events[0]; e.Payload != usedJSON || e.RawPayload != stored || e.PayloadError != "" {
+		t.Errorf("decoded event = %+v", e)
+	}
+	if e := events[1]; e.Payload != "AAAA" || e.RawPayload != "" || e.PayloadError == "" {
+		t.Errorf("undecodable event = %+v", e)
+	}
+	if e := events[2]; e.Payload != stored || e.RawPayload != "" || e.PayloadDecoding != "" || e.PayloadError != "" {
+		t.Errorf("unmapped event = %+v", e)
+	}
+}
@@ -0,0 +1,109 @@
+package payload
+
+import (
+	"drill/models"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protobufStep decodes protobuf messages using the types in a
+// FileDescriptorSet, as written by protoc --descriptor_set_out
+type protobufStep struct {
+	message  protoreflect.MessageDescriptor            // for aliases without their own
+	messages map[string]protoreflect.MessageDescriptor // alias to type
+}
+
+func newProtobufStep(cfg models.PayloadDecoderConfig) (Step, error) {
+	if cfg.Schema != "" {
+		return nil, fmt.Errorf("protobuf takes a descriptor, not a schema")
+	}
+	if cfg.Descriptor == "" {
+		return nil, fmt.Errorf("protobuf requires a descriptor, e.g. from protoc --descriptor_set_out")
+	}
+	if cfg.Message == "" && len(cfg.Aliases) == 0 {
+		return nil, fmt.Errorf("protobuf requires a message type or aliases")
+	}
+
+	files, err := loadDescriptorSet(cfg.Descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.Descriptor, err)
+	}
+	find := func(name string) (protoreflect.MessageDescriptor, error) {
+		d, err := files.FindDescriptorByName(protoreflect.FullName(strings.TrimPrefix(name, ".")))
+		if err != nil {
+			return nil, fmt.Errorf("message type %q not found in %s", name, cfg.Descriptor)
+		}
+		md, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%q in %s is not a message type", name, cfg.Descriptor)
+		}
+		return md, nil
+	}
+
+	s := &protobufStep{messages: make(map[string]protoreflect.MessageDescriptor, len(cfg.Aliases))}
+	if cfg.Message != "" {
+		if s.message, err = find(cfg.Message); err != nil {
+			return nil, err
+		}
+	}
+	for alias, name := range cfg.Aliases {
+		if s.messages[alias], err = find(name); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// loadDescriptorSet reads a serialized google.protobuf.FileDescriptorSet
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	if len(set.File) == 0 {
+		return nil, fmt.Errorf("no files found, expected a descriptor set from protoc --descriptor_set_out")
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("%w (was it written with --include_imports?)", err)
+	}
+	return files, nil
+}
+
+func (s *protobufStep) Decode(data []byte, alias string) ([]byte, string, error) {
+	md, ok := s.messages[alias]
+	if !ok {
+		md = s.message
+	}
+	if md == nil {
+		return data, "", ErrSkip
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, "", fmt.Errorf("protobuf %s: %w", md.FullName(), err)
+	}
+	out, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, "", fmt.Errorf("protobuf %s: %w", md.FullName(), err)
+	}
+	// protojson varies its spacing between runs, so rewrite it compactly
+	v, err := parseJSON(out)
+	if err != nil {
+		return nil, "", fmt.Errorf("protobuf %s: %w", md.FullName(), err)
+	}
+	return encodeJSON(v), "protobuf " + string(md.FullName()), nil
+}
@@ -0,0 +1,123 @@
+package projection
+
+import (
+	"drill/diff"
+	"drill/models"
+	"sort"
+	"strings"
+)
+
+// Projector folds events using the reducer configured for each event alias
+type Projector struct {
+	byAlias  map[string]Reducer
+	fallback Reducer
+}
+
+// New builds a projector from reducers keyed by event alias. Aliases
+// without one use the merge reducer.
+func New(reducers map[string]models.ReducerConfig) (*Projector, error) {
+	fallback, err := NewReducer(models.ReducerConfig{})
+	if err != nil {
+		return nil, err
+	}
+	p := &Projector{byAlias: make(map[string]Reducer, len(reducers)), fallback: fallback}
+	for alias, cfg := range reducers {
+		r, err := NewReducer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.byAlias[alias] = r
+	}
+	return p, nil
+}
+
+// Step is the state of an aggregate either side of one of its events
+type Step struct {
+	Before interface{} // nil before the first event
+	After  interface{}
+	Index  int // position of the event in its aggregate's history, from 0
+	Count  int // events in the aggregate's history
+}
+
+// Changes lists what the event changed in the state
+func (s Step) Changes() []diff.Change {
+	return diff.Values(s.Before, s.After)
+}
+
+// Replay folds the events of each aggregate once, on first use, so that
+// moving between events does not refold the whole history
+type Replay struct {
+	projector *Projector
+	events    []models.Event
+	histories map[string]*history
+}
+
+type history struct {
+	events []int         // positions in Replay's events, in persistedAt order
+	states []interface{} // states[i] is the state after events[i]
+}
+
+// Replay prepares to fold events, which may span several aggregates and
+// need not be sorted
+func (p *Projector) Replay(events []models.Event) *Replay {
+	return &Replay{projector: p, events: events, histories: make(map[string]*history)}
+}
+
+// At returns the states either side of the event at position pos in the
+// events given to Replay, and false when pos is out of range. Events are
+// found by position since IDs can repeat across services and snapshots.
+func (r *Replay) At(pos int) (Step, bool) {
+	if pos < 0 || pos >= len(r.events) {
+		return Step{}, false
+	}
+	h := r.history(r.events[pos].Metadata.AggregateID)
+	for i, p := range h.events {
+		if p != pos {
+			continue
+		}
+		step := Step{After: h.states[i], Index: i, Count: len(h.events)}
+		if i > 0 {
+			step.Before = h.states[i-1]
+		}
+		return step, true
+	}
+	return Step{}, false
+}
+
+func (r *Replay) history(aggregateID string) *history {
+	if h, ok := r.histories[aggregateID]; ok {
+		return h
+	}
+
+	h := &history{}
+	for i, e := range r.events {
+		if e.Metadata.AggregateID == aggregateID {
+			h.events = append(h.events, i)
+		}
+	}
+	sort.SliceStable(h.events, func(i, j int) bool {
+		return r.events[h.events[i]].Metadata.PersistedAt.Before(r.events[h.events[j]].Metadata.PersistedAt)
+	})
+
+	var state interface{}
+	h.states = make([]interface{}, len(h.events))
+	for i, pos := range h.events {
+		state = r.projector.apply(state, r.events[pos])
+		h.states[i] = state
+	}
+
+	r.histories[aggregateID] = h
+	return h
+}
+
+// apply folds one event, leaving the state alone when it has no payload
+func (p *Projector) apply(state interface{}, e models.Event) interface{} {
+	if strings.TrimSpace(e.Payload) == "" {
+		return state
+	}
+	r, ok := p.byAlias[e.Metadata.EventAlias]
+	if !ok {
+		r = p.fallback
+	}
+	return r.Apply(state, diff.Parse(e.Payload))
+}
@@ -0,0 +1,137 @@
+// Package projection reconstructs the state of an aggregate by folding its
+// event payloads, in order, into a running JSON value.
+package projection
+
+import (
+	"drill/models"
+	"fmt"
+	"strings"
+)
+
+// Reducer folds one event's decoded payload into the state. It must not
+// modify state in place, since earlier states are kept for comparison.
+type Reducer interface {
+	Apply(state, payload interface{}) interface{}
+}
+
+// DefaultReducer is used for events without a configured reducer
+const DefaultReducer = "merge"
+
+// NewReducer returns the reducer described by cfg
+func NewReducer(cfg models.ReducerConfig) (Reducer, error) {
+	path := splitPath(cfg.Path)
+	switch cfg.Type {
+	case "", DefaultReducer:
+		return pathReducer(path, merge), nil
+	case "replace":
+		return pathReducer(path, func(_, payload interface{}) interface{} { return payload }), nil
+	case "append":
+		return pathReducer(path, appendTo), nil
+	case "remove":
+		return removeReducer(path), nil
+	case "ignore":
+		return reducerFunc(func(state, _ interface{}) interface{} { return state }), nil
+	}
+	return nil, fmt.Errorf("unknown reducer %q, expected one of: append, ignore, merge, remove, replace", cfg.Type)
+}
+
+type reducerFunc func(state, payload interface{}) interface{}
+
+func (f reducerFunc) Apply(state, payload interface{}) interface{} {
+	return f(state, payload)
+}
+
+// pathReducer applies fn to the value at path rather than to the whole
+// state
+func pathReducer(path []string, fn reducerFunc) Reducer {
+	return reducerFunc(func(state, payload interface{}) interface{} {
+		return update(state, path, func(v interface{}) interface{} { return fn(v, payload) })
+	})
+}
+
+// removeReducer deletes the value at path, or resets the state to an empty
+// object when no path is given
+func removeReducer(path []string) Reducer {
+	return reducerFunc(func(state, _ interface{}) interface{} {
+		if len(path) == 0 {
+			return map[string]interface{}{}
+		}
+		parent := path[:len(path)-1]
+		key := path[len(path)-1]
+		return update(state, parent, func(v interface{}) interface{} {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return v
+			}
+			out := copyObject(obj)
+			delete(out, key)
+			return out
+		})
+	})
+}
+
+// merge deep-merges patch into target following JSON merge patch rules:
+// objects merge key by key, a null value deletes the key and anything else
+// replaces the target
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	out := copyObject(t)
+	for k, v := range p {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		out[k] = merge(out[k], v)
+	}
+	return out
+}
+
+// appendTo adds the payload to the array, starting one if needed. An array
+// payload adds each of its items.
+func appendTo(target, payload interface{}) interface{} {
+	arr, _ := target.([]interface{})
+	out := make([]interface{}, len(arr), len(arr)+1)
+	copy(out, arr)
+	if items, ok := payload.([]interface{}); ok {
+		return append(out, items...)
+	}
+	return append(out, payload)
+}
+
+// update returns a copy of state with fn applied at path, creating objects
+// along the path as needed
+func update(state interface{}, path []string, fn func(interface{}) interface{}) interface{} {
+	if len(path) == 0 {
+		return fn(state)
+	}
+	obj, ok := state.(map[string]interface{})
+	if !ok {
+		obj = map[string]interface{}{}
+	}
+	out := copyObject(obj)
+	out[path[0]] = update(obj[path[0]], path[1:], fn)
+	return out
+}
+
+func copyObject(obj map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj)+1)
+	for k, v := range obj {
+		out[k] = v
+	}
+	return out
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
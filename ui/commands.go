@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"drill/models"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// CreateStatusStyle returns a style coloured by the command's outcome
+func CreateStatusStyle(status models.CommandStatus) lipgloss.Style {
+	if status == models.CommandFailed {
+		return FailedCommandStyle.Bold(true)
+	}
+	return SuccessCommandStyle.Bold(true)
+}
+
+func (m Model) renderCommands() string {
+	if len(m.Commands) == 0 {
+		return "No commands found"
+	}
+
+	const (
+		timeWidth    = 22
+		serviceWidth = 20
+		cmdWidth     = 22
+		statusWidth  = 21
+		corrWidth    = 38
+		cellPadding  = "  " // spacing between cells
+	)
+
+	var sb strings.Builder
+
+	// Header row
+	timeCol := lipgloss.NewStyle().Width(timeWidth).Render("Time")
+	svcCol := lipgloss.NewStyle().Width(serviceWidth).Render("Service")
+	cmdCol := lipgloss.NewStyle().Width(cmdWidth).Render("Command")
+	statusCol := lipgloss.NewStyle().Width(statusWidth).Render("Status")
+	corrCol := lipgloss.NewStyle().Width(corrWidth).Render("CorrelationID")
+
+	header := lipgloss.JoinHorizontal(lipgloss.Left, timeCol, cellPadding, svcCol, cellPadding, cmdCol, cellPadding, statusCol, cellPadding, corrCol)
+	sb.WriteString(TableHeaderStyle.Render(header))
+	sb.WriteString("\n")
+
+	for i, cmd := range m.Commands {
+		timeStr := cmd.PersistedAt.Format("2006-01-02 15:04:05")
+		timeCell := lipgloss.NewStyle().Width(timeWidth).Render(timeStr)
+
+		svcText := CreateServiceStyle(cmd.ServiceName).Render(cmd.ServiceName)
+		svcCell := lipgloss.NewStyle().Width(serviceWidth).Render(svcText)
+
+		cmdAlias := cmd.CommandAlias
+		if len(cmdAlias) > cmdWidth-2 {
+			cmdAlias = cmdAlias[:cmdWidth-5] + "..."
+		}
+		cmdCell := lipgloss.NewStyle().Width(cmdWidth).Render(cmdAlias)
+
+		statusText := CreateStatusStyle(cmd.CommandStatus).Render(string(cmd.CommandStatus))
+		statusCell := lipgloss.NewStyle().Width(statusWidth).Render(statusText)
+
+		correlationText := CreateCorrelationStyle(cmd.CorrelationID).Render(cmd.CorrelationID)
+		corrCell := lipgloss.NewStyle().Width(corrWidth).Render(correlationText)
+
+		row := lipgloss.JoinHorizontal(lipgloss.Left, timeCell, cellPadding, svcCell, cellPadding, cmdCell, cellPadding, statusCell, cellPadding, corrCell)
+
+		if i == m.selectedIndex {
+			row = SelectedRowStyle.Render(row)
+		}
+
+		sb.WriteString(row)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func (m Model) renderCommandDetail() string {
+	if len(m.Commands) == 0 || m.selectedIndex >= len(m.Commands) {
+		return "No command selected"
+	}
+
+	return formatCommandDetail(m.Commands[m.selectedIndex], m.payloadView())
+}
+
+// formatCommandDetail renders the detail pane contents for a single command,
+// ending with the already rendered payload
+func formatCommandDetail(cmd models.Command, payload string) string {
+	var sb strings.Builder
+
+	// Title
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#ffffff")).MarginBottom(1)
+	sb.WriteString(titleStyle.Render(cmd.CommandAlias))
+	sb.WriteString("\n\n")
+
+	// Labels
+	labelStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#888888"))
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffffff"))
+
+	// Command ID
+	sb.WriteString(labelStyle.Render("Command ID:"))
+	sb.WriteString("\n")
+	sb.WriteString(valueStyle.Render(cmd.CommandID))
+	sb.WriteString("\n\n")
+
+	// Status
+	sb.WriteString(labelStyle.Render("Status:"))
+	sb.WriteString("\n")
+	sb.WriteString(CreateStatusStyle(cmd.CommandStatus).Render(string(cmd.CommandStatus)))
+	sb.WriteString("\n\n")
+
+	// Service
+	sb.WriteString(labelStyle.Render("Service:"))
+	sb.WriteString("\n")
+	sb.WriteString(CreateServiceStyle(cmd.ServiceName).Render(cmd.ServiceName))
+	sb.WriteString("\n\n")
+
+	// Persisted At
+	sb.WriteString(labelStyle.Render("Persisted At:"))
+	sb.WriteString("\n")
+	sb.WriteString(valueStyle.Render(cmd.PersistedAt.Format("2006-01-02 15:04:05.000")))
+	sb.WriteString("\n\n")
+
+	// Correlation ID
+	sb.WriteString(labelStyle.Render("Correlation ID:"))
+	sb.WriteString("\n")
+	sb.WriteString(CreateCorrelationStyle(cmd.CorrelationID).Render(cmd.CorrelationID))
+	sb.WriteString("\n\n")
+
+	// Aggregate ID
+	sb.WriteString(labelStyle.Render("Aggregate ID:"))
+	sb.WriteString("\n")
+	sb.WriteString(valueStyle.Render(cmd.AggregateID))
+	sb.WriteString("\n\n")
+
+	// Payload
+	sb.WriteString(payloadHeading(cmd.PayloadDecoding, cmd.PayloadError, labelStyle))
+	sb.WriteString("\n")
+	sb.WriteString(payload)
+	sb.WriteString(storedPayload(cmd.RawPayload, labelStyle))
+
+	return sb.String()
+}
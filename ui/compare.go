@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"drill/cache"
+	"drill/models"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// compareItem is a cached request, or an earlier snapshot of one, that can
+// be picked as a side of a comparison
+type compareItem struct {
+	label    string
+	fetched  string
+	events   []models.Event
+	commands []models.Command
+}
+
+// requestLabel names a cached request as in the previous requests list,
+// e.g. "<id> [staging] (correlation)"
+func requestLabel(req cache.CachedRequest) string {
+	label := req.AggregateID
+	if req.IsMock {
+		label += " [MOCK]"
+	} else if req.Environment != "" {
+		label += fmt.Sprintf(" [%s]", req.Environment)
+	}
+	if req.Lookup != "" {
+		label += fmt.Sprintf(" (%s)", req.Lookup)
+	}
+	return label
+}
+
+// compareItems lists every cached request followed by its earlier snapshots
+func (m EntryModel) compareItems() []compareItem {
+	var items []compareItem
+	for _, req := range m.cache.Requests {
+		label := requestLabel(req)
+		items = append(items, compareItem{
+			label:    label,
+			fetched:  req.Timestamp.Format("Jan 02 15:04:05"),
+			events:   req.Events,
+			commands: req.Commands,
+		})
+		for _, snap := range req.Previous {
+			items = append(items, compareItem{
+				label:    label,
+				fetched:  snap.Timestamp.Format("Jan 02 15:04:05"),
+				events:   snap.Events,
+				commands: snap.Commands,
+			})
+		}
+	}
+	return items
+}
+
+// openComparePicker starts picking the two sides of a comparison
+func (m EntryModel) openComparePicker() EntryModel {
+	if len(m.compareItems()) < 2 {
+		m.err = fmt.Errorf("comparing needs at least two cached requests or snapshots")
+		return m
+	}
+	m.err = nil
+	m.comparePicker = true
+	m.compareIndex = 0
+	m.compareFirst = -1
+	return m
+}
+
+// updateComparePicker handles keys while picking: the first Enter marks the
+// left side and the second opens the diff against the right side
+func (m EntryModel) updateComparePicker(key string) (tea.Model, tea.Cmd) {
+	items := m.compareItems()
+
+	switch key {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.compareIndex > 0 {
+			m.compareIndex--
+		}
+	case "down", "j":
+		if m.compareIndex < len(items)-1 {
+			m.compareIndex++
+		}
+	case "enter":
+		if m.compareFirst < 0 {
+			m.compareFirst = m.compareIndex
+			return m, nil
+		}
+		if m.compareFirst == m.compareIndex {
+			m.err = fmt.Errorf("pick a different request or snapshot to compare against")
+			return m, nil
+		}
+		m.comparePicker = false
+		m.err = nil
+		dm := NewDiffModel(m.cfg, items[m.compareFirst], items[m.compareIndex])
+		return dm, func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		}
+	case "esc":
+		m.comparePicker = false
+		m.err = nil
+	}
+	return m, nil
+}
+
+func (m EntryModel) renderComparePicker() string {
+	var sb strings.Builder
+
+	for i, item := range m.compareItems() {
+		style := lipgloss.NewStyle().Padding(0, 2)
+		if i == m.compareIndex {
+			style = style.
+				Background(lipgloss.Color("#5c6bc0")).
+				Foreground(lipgloss.Color("#ffffff")).
+				Bold(true)
+		}
+
+		marker := "  "
+		if i == m.compareFirst {
+			marker = "◀ "
+		}
+		sb.WriteString(style.Render(fmt.Sprintf("%s%s  %s", marker, item.label, item.fetched)))
+		sb.WriteString("\n")
+	}
+
+	help := "Press Enter to pick the first side, Esc to cancel"
+	if m.compareFirst >= 0 {
+		help = "Press Enter to pick the side to compare against, Esc to cancel"
+	}
+	sb.WriteString(HelpStyle.Render(help))
+	return sb.String()
+}
@@ -0,0 +1,345 @@
+package ui
+
+import (
+	"drill/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// correlationCommand is a command together with the events that followed it
+// under the same correlation ID
+type correlationCommand struct {
+	Command *models.Command
+	Events  []*models.Event
+}
+
+// correlationGroup collects everything that shares a correlation ID. Events
+// persisted before any command in the group are kept as orphans.
+type correlationGroup struct {
+	CorrelationID string
+	Commands      []correlationCommand
+	Orphans       []*models.Event
+}
+
+type treeRowKind int
+
+const (
+	treeRowGroup treeRowKind = iota
+	treeRowCommand
+	treeRowEvent
+)
+
+// treeRow is one visible line of the flattened correlation tree
+type treeRow struct {
+	kind    treeRowKind
+	group   int
+	key     string
+	command *models.Command
+	event   *models.Event
+}
+
+// buildCorrelationGroups walks the timeline in order, attaching each event to
+// the most recent command seen for its correlation ID
+func buildCorrelationGroups(timeline []timelineEntry) []correlationGroup {
+	var groups []correlationGroup
+	index := make(map[string]int)
+
+	for _, entry := range timeline {
+		corrID := entry.correlationID()
+		gi, ok := index[corrID]
+		if !ok {
+			gi = len(groups)
+			index[corrID] = gi
+			groups = append(groups, correlationGroup{CorrelationID: corrID})
+		}
+		g := &groups[gi]
+
+		if entry.Command != nil {
+			g.Commands = append(g.Commands, correlationCommand{Command: entry.Command})
+			continue
+		}
+		if len(g.Commands) == 0 {
+			g.Orphans = append(g.Orphans, entry.Event)
+			continue
+		}
+		last := &g.Commands[len(g.Commands)-1]
+		last.Events = append(last.Events, entry.Event)
+	}
+
+	return groups
+}
+
+func groupKey(corrID string) string {
+	return "corr/" + corrID
+}
+
+// commandKey identifies a command row by its group and position as well as
+// its ID, so commands with empty or repeated IDs collapse independently
+func commandKey(group, index int, cmd *models.Command) string {
+	return fmt.Sprintf("cmd/%d/%d/%s", group, index, cmd.CommandID)
+}
+
+// buildTreeRows flattens the groups into the rows currently visible given
+// the collapsed set
+func buildTreeRows(groups []correlationGroup, collapsed map[string]bool) []treeRow {
+	var rows []treeRow
+	for gi, g := range groups {
+		gKey := groupKey(g.CorrelationID)
+		rows = append(rows, treeRow{kind: treeRowGroup, group: gi, key: gKey})
+		if collapsed[gKey] {
+			continue
+		}
+		for _, evt := range g.Orphans {
+			rows = append(rows, treeRow{kind: treeRowEvent, group: gi, event: evt})
+		}
+		for ci, cc := range g.Commands {
+			cKey := commandKey(gi, ci, cc.Command)
+			rows = append(rows, treeRow{kind: treeRowCommand, group: gi, key: cKey, command: cc.Command})
+			if collapsed[cKey] {
+				continue
+			}
+			for _, evt := range cc.Events {
+				rows = append(rows, treeRow{kind: treeRowEvent, group: gi, event: evt})
+			}
+		}
+	}
+	return rows
+}
+
+// setCollapsed collapses or expands the node under the cursor. Collapsing an
+// event row collapses its parent instead and moves the cursor onto it.
+func (m *Model) setCollapsed(collapse bool) {
+	if m.selectedIndex >= len(m.treeRows) {
+		return
+	}
+	row := m.treeRows[m.selectedIndex]
+
+	key := row.key
+	if row.kind == treeRowEvent {
+		if !collapse {
+			return
+		}
+		// Find the nearest parent row above
+		for i := m.selectedIndex - 1; i >= 0; i-- {
+			if m.treeRows[i].kind != treeRowEvent {
+				key = m.treeRows[i].key
+				m.selectedIndex = i
+				break
+			}
+		}
+	}
+
+	if m.collapsed == nil {
+		m.collapsed = make(map[string]bool)
+	}
+	m.collapsed[key] = collapse
+	m.treeRows = buildTreeRows(m.correlationGroups, m.collapsed)
+	if m.selectedIndex >= len(m.treeRows) {
+		m.selectedIndex = len(m.treeRows) - 1
+	}
+}
+
+// toggleCollapsed flips the collapsed state of the node under the cursor
+func (m *Model) toggleCollapsed() {
+	if m.selectedIndex >= len(m.treeRows) {
+		return
+	}
+	row := m.treeRows[m.selectedIndex]
+	if row.kind == treeRowEvent {
+		return
+	}
+	m.setCollapsed(!m.collapsed[row.key])
+}
+
+func (m Model) renderCorrelationTree() string {
+	if len(m.treeRows) == 0 {
+		return "No commands or events found"
+	}
+
+	var sb strings.Builder
+
+	header := TableHeaderStyle.Render("Correlation / Command / Event")
+	sb.WriteString(header)
+	sb.WriteString("\n")
+
+	for i, row := range m.treeRows {
+		var line string
+
+		switch row.kind {
+		case treeRowGroup:
+			g := m.correlationGroups[row.group]
+			marker := "▾"
+			if m.collapsed[row.key] {
+				marker = "▸"
+			}
+			corrID := g.CorrelationID
+			if corrID == "" {
+				corrID = "(no correlation ID)"
+			}
+			cmds, evts := g.counts()
+			line = fmt.Sprintf("%s %s %s",
+				marker,
+				CreateCorrelationStyle(g.CorrelationID).Render(corrID),
+				HelpStyle.UnsetMarginTop().Render(fmt.Sprintf("(%d cmds, %d events)", cmds, evts)),
+			)
+
+		case treeRowCommand:
+			marker := "▾"
+			if m.collapsed[row.key] {
+				marker = "▸"
+			}
+			tag := CommandTagStyle.Render("CMD")
+			alias := row.command.CommandAlias
+			if row.command.CommandStatus == models.CommandFailed {
+				tag = FailedCommandStyle.Bold(true).Render("CMD")
+				alias = FailedCommandStyle.Render(alias)
+			}
+			line = fmt.Sprintf("  %s %s %s  %s  %s",
+				marker,
+				tag,
+				row.command.PersistedAt.Format("15:04:05"),
+				CreateServiceStyle(row.command.ServiceName).Render(row.command.ServiceName),
+				alias,
+			)
+
+		case treeRowEvent:
+			indent := "      "
+			if i > 0 && m.parentIsGroup(i) {
+				indent = "    "
+			}
+			line = fmt.Sprintf("%s%s %s  %s  %s",
+				indent,
+				EventTagStyle.Render("EVT"),
+				row.event.Metadata.PersistedAt.Format("15:04:05"),
+				CreateServiceStyle(row.event.ServiceName).Render(row.event.ServiceName),
+				row.event.Metadata.EventAlias,
+			)
+		}
+
+		if i == m.selectedIndex {
+			line = SelectedRowStyle.Render(line)
+		}
+
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// parentIsGroup reports whether the event row at i hangs directly off its
+// correlation group rather than a command
+func (m Model) parentIsGroup(i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch m.treeRows[j].kind {
+		case treeRowGroup:
+			return true
+		case treeRowCommand:
+			return false
+		}
+	}
+	return true
+}
+
+func (g correlationGroup) counts() (int, int) {
+	events := len(g.Orphans)
+	for _, cc := range g.Commands {
+		events += len(cc.Events)
+	}
+	return len(g.Commands), events
+}
+
+func (m Model) renderCorrelationDetail() string {
+	if len(m.treeRows) == 0 || m.selectedIndex >= len(m.treeRows) {
+		return "Nothing selected"
+	}
+
+	row := m.treeRows[m.selectedIndex]
+	switch row.kind {
+	case treeRowCommand:
+		return formatCommandDetail(*row.command, m.payloadView())
+	case treeRowEvent:
+		return m.eventDetail(row.event)
+	}
+
+	return formatCorrelationGroup(m.correlationGroups[row.group])
+}
+
+// formatCorrelationGroup summarises a correlation group for the detail pane
+func formatCorrelationGroup(g correlationGroup) string {
+	var sb strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#ffffff")).MarginBottom(1)
+	sb.WriteString(titleStyle.Render("Correlation"))
+	sb.WriteString("\n\n")
+
+	labelStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#888888"))
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffffff"))
+
+	// Correlation ID
+	sb.WriteString(labelStyle.Render("Correlation ID:"))
+	sb.WriteString("\n")
+	sb.WriteString(CreateCorrelationStyle(g.CorrelationID).Render(g.CorrelationID))
+	sb.WriteString("\n\n")
+
+	// Counts
+	cmds, evts := g.counts()
+	failed := 0
+	for _, cc := range g.Commands {
+		if cc.Command.CommandStatus == models.CommandFailed {
+			failed++
+		}
+	}
+	sb.WriteString(labelStyle.Render("Contents:"))
+	sb.WriteString("\n")
+	sb.WriteString(valueStyle.Render(fmt.Sprintf("%d commands (%d failed), %d events", cmds, failed, evts)))
+	sb.WriteString("\n\n")
+
+	// Services touched, in order of first appearance
+	var services []string
+	seen := make(map[string]bool)
+	var first, last time.Time
+	note := func(svc string, at time.Time) {
+		if !seen[svc] {
+			seen[svc] = true
+			services = append(services, svc)
+		}
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	for _, evt := range g.Orphans {
+		note(evt.ServiceName, evt.Metadata.PersistedAt)
+	}
+	for _, cc := range g.Commands {
+		note(cc.Command.ServiceName, cc.Command.PersistedAt)
+		for _, evt := range cc.Events {
+			note(evt.ServiceName, evt.Metadata.PersistedAt)
+		}
+	}
+
+	sb.WriteString(labelStyle.Render("Services:"))
+	sb.WriteString("\n")
+	for _, svc := range services {
+		sb.WriteString(CreateServiceStyle(svc).Render(svc))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+
+	// Time span
+	sb.WriteString(labelStyle.Render("Span:"))
+	sb.WriteString("\n")
+	sb.WriteString(valueStyle.Render(fmt.Sprintf("%s → %s (%s)",
+		first.Format("2006-01-02 15:04:05.000"),
+		last.Format("2006-01-02 15:04:05.000"),
+		last.Sub(first).Round(time.Millisecond),
+	)))
+
+	return sb.String()
+}
@@ -0,0 +1,335 @@
+package ui
+
+import (
+	"drill/config"
+	"drill/diff"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DiffModel shows two cached histories side by side, lined up entry by
+// entry, with the field-level payload differences of the selected row
+type DiffModel struct {
+	cfg            *config.Config
+	left           compareItem
+	right          compareItem
+	commands       bool // comparing commands rather than events
+	rows           []diff.Row
+	selectedIndex  int
+	listViewport   viewport.Model
+	detailViewport viewport.Model
+	width          int
+	height         int
+	ready          bool
+}
+
+// NewDiffModel compares the events of left and right
+func NewDiffModel(cfg *config.Config, left, right compareItem) DiffModel {
+	m := DiffModel{cfg: cfg, left: left, right: right}
+	m.rows = m.buildRows()
+	return m
+}
+
+func (m DiffModel) buildRows() []diff.Row {
+	if m.commands {
+		return diff.Histories(diff.CommandEntries(m.left.commands), diff.CommandEntries(m.right.commands))
+	}
+	return diff.Histories(diff.EventEntries(m.left.events), diff.EventEntries(m.right.events))
+}
+
+func (m DiffModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m DiffModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			entry := NewEntryModel(m.cfg)
+			return entry, func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			}
+		case "tab", "shift+tab":
+			m.commands = !m.commands
+			m.rows = m.buildRows()
+			m.selectedIndex = 0
+		case "up", "k":
+			if m.selectedIndex > 0 {
+				m.selectedIndex--
+			}
+		case "down", "j":
+			if m.selectedIndex < len(m.rows)-1 {
+				m.selectedIndex++
+			}
+		case "n":
+			m.jumpDifference(1)
+		case "N":
+			m.jumpDifference(-1)
+		case "home", "g":
+			m.selectedIndex = 0
+		case "end", "G":
+			m.selectedIndex = max(len(m.rows)-1, 0)
+		}
+		m.updateViews()
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+		// title, summary line and panel headers above, stats and help below
+		availableHeight := m.height - 5 - 3
+		leftWidth := m.width / 2
+		rightWidth := m.width - leftWidth - 3
+
+		if !m.ready {
+			m.listViewport = viewport.New(leftWidth, availableHeight)
+			m.detailViewport = viewport.New(rightWidth, availableHeight)
+			m.ready = true
+		} else {
+			m.listViewport.Width = leftWidth
+			m.listViewport.Height = availableHeight
+			m.detailViewport.Width = rightWidth
+			m.detailViewport.Height = availableHeight
+		}
+		m.updateViews()
+	}
+
+	var cmd tea.Cmd
+	m.detailViewport, cmd = m.detailViewport.Update(msg)
+	return m, cmd
+}
+
+// jumpDifference moves to the next (dir 1) or previous (dir -1) row that
+// differs, wrapping around
+func (m *DiffModel) jumpDifference(dir int) {
+	n := len(m.rows)
+	if n == 0 {
+		return
+	}
+	for step := 1; step <= n; step++ {
+		i := ((m.selectedIndex+dir*step)%n + n) % n
+		if m.rows[i].Kind != 0 {
+			m.selectedIndex = i
+			return
+		}
+	}
+}
+
+func (m *DiffModel) updateViews() {
+	m.listViewport.SetContent(m.renderRows())
+	m.detailViewport.SetContent(m.renderRowDetail())
+	m.detailViewport.GotoTop()
+
+	visibleLines := m.listViewport.Height - 2
+	if m.selectedIndex >= visibleLines {
+		m.listViewport.SetYOffset(m.selectedIndex - visibleLines + 1)
+	} else {
+		m.listViewport.SetYOffset(0)
+	}
+}
+
+// diffMarker returns the gutter symbol and style for a row
+func diffMarker(row diff.Row) (string, lipgloss.Style) {
+	switch row.Kind {
+	case diff.Added:
+		return "+", DiffAddedStyle
+	case diff.Removed:
+		return "-", DiffRemovedStyle
+	case diff.Changed:
+		return "~", DiffChangedStyle
+	}
+	return " ", lipgloss.NewStyle()
+}
+
+func (m DiffModel) renderRows() string {
+	if len(m.rows) == 0 {
+		return "Nothing to compare"
+	}
+
+	colWidth := max((m.listViewport.Width-4)/2, 10)
+	cell := lipgloss.NewStyle().Width(colWidth).MaxWidth(colWidth)
+
+	var sb strings.Builder
+	header := lipgloss.JoinHorizontal(lipgloss.Left, "  ", cell.Render("Left"), " ", cell.Render("Right"))
+	sb.WriteString(TableHeaderStyle.Render(header))
+	sb.WriteString("\n")
+
+	for i, row := range m.rows {
+		marker, style := diffMarker(row)
+		line := lipgloss.JoinHorizontal(lipgloss.Left,
+			style.Render(marker)+" ",
+			cell.Render(diffCell(row.Left, style)),
+			" ",
+			cell.Render(diffCell(row.Right, style)),
+		)
+		if i == m.selectedIndex {
+			line = SelectedRowStyle.Render(line)
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// diffCell shows an entry's alias followed by its service, if known
+func diffCell(e *diff.Entry, style lipgloss.Style) string {
+	if e == nil {
+		return ""
+	}
+	if e.Service == "" {
+		return style.Render(e.Alias)
+	}
+	return style.Render(e.Alias) + " " + CreateServiceStyle(e.Service).Render(e.Service)
+}
+
+func (m DiffModel) renderRowDetail() string {
+	if m.selectedIndex >= len(m.rows) {
+		return "No entry selected"
+	}
+	row := m.rows[m.selectedIndex]
+
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#ffffff")).MarginBottom(1)
+	labelStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#888888"))
+
+	switch row.Kind {
+	case diff.Added:
+		sb.WriteString(titleStyle.Render("Only on the right: " + row.Right.Alias))
+	case diff.Removed:
+		sb.WriteString(titleStyle.Render("Missing on the right: " + row.Left.Alias))
+	case diff.Changed:
+		sb.WriteString(titleStyle.Render("Changed: " + row.Left.Alias))
+	default:
+		sb.WriteString(titleStyle.Render("Same: " + row.Left.Alias))
+	}
+	sb.WriteString("\n\n")
+
+	for _, side := range []struct {
+		label string
+		entry *diff.Entry
+	}{{"Left:", row.Left}, {"Right:", row.Right}} {
+		if side.entry == nil {
+			continue
+		}
+		sb.WriteString(labelStyle.Render(side.label))
+		sb.WriteString("\n")
+		sb.WriteString(side.entry.Alias)
+		if side.entry.Service != "" {
+			sb.WriteString(" " + CreateServiceStyle(side.entry.Service).Render(side.entry.Service))
+		}
+		sb.WriteString(fmt.Sprintf("\n%s | %s\n\n", side.entry.ID,
+			side.entry.PersistedAt.Format("2006-01-02 15:04:05.000")))
+	}
+
+	if row.Left == nil || row.Right == nil {
+		sb.WriteString(labelStyle.Render("Payload:"))
+		sb.WriteString("\n")
+		if row.Left != nil {
+			sb.WriteString(renderPayload(row.Left.Payload))
+		} else {
+			sb.WriteString(renderPayload(row.Right.Payload))
+		}
+		return sb.String()
+	}
+
+	if row.AliasChanged() {
+		sb.WriteString(DiffChangedStyle.Render(fmt.Sprintf("~ alias: %s → %s", row.Left.Alias, row.Right.Alias)))
+		sb.WriteString("\n")
+	}
+	if row.StatusChanged() {
+		sb.WriteString(DiffChangedStyle.Render(fmt.Sprintf("~ status: %s → %s", row.Left.Status, row.Right.Status)))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(labelStyle.Render("Payload differences:"))
+	sb.WriteString("\n")
+	if len(row.Changes) == 0 {
+		sb.WriteString(HelpStyle.UnsetMarginTop().Render("(none)"))
+		return sb.String()
+	}
+	sb.WriteString(renderChanges(row.Changes))
+	return sb.String()
+}
+
+// renderChanges lists payload field changes, one per line, coloured by kind
+func renderChanges(changes []diff.Change) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		path := c.Path
+		if path == "" {
+			path = "(payload)"
+		}
+		switch c.Kind {
+		case diff.Added:
+			sb.WriteString(DiffAddedStyle.Render(fmt.Sprintf("+ %s: %s", path, diff.Format(c.New))))
+		case diff.Removed:
+			sb.WriteString(DiffRemovedStyle.Render(fmt.Sprintf("- %s: %s", path, diff.Format(c.Old))))
+		default:
+			sb.WriteString(DiffChangedStyle.Render(fmt.Sprintf("~ %s: %s → %s", path, diff.Format(c.Old), diff.Format(c.New))))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// renderSummary counts the rows by kind, e.g. "Events: 40 same, 2 changed,
+// 1 added, 0 missing"
+func (m DiffModel) renderSummary() string {
+	s := diff.Summarize(m.rows)
+	kind := "Events"
+	if m.commands {
+		kind = "Commands"
+	}
+	if s.Identical() {
+		return SuccessCommandStyle.Render(fmt.Sprintf("%s: identical (%d)", kind, s.Same))
+	}
+	return fmt.Sprintf("%s: %d same, %s, %s, %s", kind, s.Same,
+		DiffChangedStyle.Render(fmt.Sprintf("%d changed", s.Changed)),
+		DiffAddedStyle.Render(fmt.Sprintf("%d added", s.Added)),
+		DiffRemovedStyle.Render(fmt.Sprintf("%d missing", s.Removed)))
+}
+
+func (m DiffModel) View() string {
+	if !m.ready {
+		return "Initializing..."
+	}
+
+	title := renderTitle(fmt.Sprintf("Compare - %s @ %s vs %s @ %s",
+		m.left.label, m.left.fetched, m.right.label, m.right.fetched), nil)
+
+	leftWidth := m.width / 2
+	rightWidth := m.width - leftWidth - 3
+
+	listTitle := "EVENTS"
+	if m.commands {
+		listTitle = "COMMANDS"
+	}
+	headers := lipgloss.JoinHorizontal(lipgloss.Top,
+		HeaderStyle.Width(leftWidth).Align(lipgloss.Center).Render(listTitle),
+		" ",
+		HeaderStyle.Width(rightWidth).Align(lipgloss.Center).Render("DIFFERENCES"),
+	)
+
+	listBox := BorderStyle.BorderForeground(lipgloss.Color("#ffcc00")).Width(leftWidth).Render(m.listViewport.View())
+	detailBox := BorderStyle.Width(rightWidth).Render(m.detailViewport.View())
+	panels := lipgloss.JoinHorizontal(lipgloss.Top, listBox, " ", detailBox)
+
+	stats := fmt.Sprintf("Selected: %d/%d", min(m.selectedIndex+1, len(m.rows)), len(m.rows))
+	help := HelpStyle.Render("j/k: navigate | n/N: next/prev difference | Tab: events/commands | Esc: back | q: quit")
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().MaxWidth(m.width).Render(title),
+		m.renderSummary(),
+		headers,
+		panels,
+		stats,
+		help,
+	)
+}
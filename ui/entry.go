@@ -1,7 +1,9 @@
 package ui
 
 import (
+	"context"
 	"drill/cache"
+	"drill/config"
 	"drill/fetcher"
 	"drill/mock"
 	"drill/models"
@@ -21,6 +23,8 @@ type menuOption int
 const (
 	optionLoadAccount menuOption = iota
 	optionMockMode
+	optionCompare
+	optionEnvironment
 )
 
 type EntryModel struct {
@@ -28,7 +32,14 @@ type EntryModel struct {
 	previousIndex   int
 	textInput       textinput.Model
 	inputMode       bool
+	lookup          models.LookupKind
+	envPicker       bool
+	envIndex        int
+	comparePicker   bool
+	compareIndex    int
+	compareFirst    int // item picked as the left side, or -1
 	cache           *cache.Cache
+	cfg             *config.Config
 	services        []models.ServiceConfig
 	width           int
 	height          int
@@ -37,36 +48,57 @@ type EntryModel struct {
 	loadingMsg      string
 	progress        progress.Model
 	progressPercent float64
-	progressSteps   []string
+	progressSteps   []progressStep
 	currentStep     int
+	steps           chan FetchStepMsg
+	cancel          context.CancelFunc
+	fetchID         int    // generation of the current fetch, messages from older ones are dropped
+	target          string // ID the current fetch looks up
 }
 
 type LoadCompleteMsg struct {
+	Fetch       int // generation of the fetch that produced it
 	AggregateID string
 	Events      []models.Event
 	Commands    []models.Command
+	Failures    []fetcher.FetchResult // calls that failed while others succeeded
 	IsMock      bool
+	Environment string
+	Lookup      models.LookupKind
+	FromCache   bool // opened from the cache, which already holds it
 }
 
 type LoadErrorMsg struct {
-	Err error
-}
-
-type ProgressMsg struct {
-	Percent float64
-	Step    string
+	Fetch int
+	Err   error
 }
 
+// FetchStepMsg is sent as each service/endpoint call finishes or fails,
+// with Done unset and the page's rows after each page it receives, and with
+// only Related set when a correlation or command lookup moves on to its
+// aggregates
 type FetchStepMsg struct {
+	Fetch       int
 	ServiceName string
 	StepType    string // "events" or "commands"
+	Target      string // the ID the call looked up
 	Done        bool
+	Err         error
+	Items       int
+	Pages       int
+	Latency     time.Duration
+	Related     []string // aggregates found by a correlation or command lookup
+
+	// Rows decoded from the page, which the data view shows while the
+	// rest of the fetch is still running
+	Events   []models.Event
+	Commands []models.Command
 }
 
-func NewEntryModel(services []models.ServiceConfig) EntryModel {
+func NewEntryModel(cfg *config.Config) EntryModel {
 	ti := textinput.New()
-	ti.Placeholder = "Enter Aggregate ID (UUID)"
-	ti.CharLimit = 36
+	ti.Placeholder = "Enter Aggregate ID"
+	ti.CharLimit = 256
 	ti.Width = 40
 
 	c, _ := cache.Load()
@@ -80,7 +112,9 @@ func NewEntryModel(services []models.ServiceConfig) EntryModel {
 		menuSelection: optionLoadAccount,
 		textInput:     ti,
 		cache:         c,
-		services:      services,
+		cfg:           cfg,
+		services:      cfg.Services,
+		lookup:        models.LookupAggregate,
 		progress:      p,
 		previousIndex: -1,
 	}
@@ -94,30 +128,55 @@ func (m EntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if m.loading {
+			switch msg.String() {
+			case "ctrl+c":
+				if m.cancel != nil {
+					m.cancel()
+				}
+				return m, tea.Quit
+			case "esc":
+				m.cancelFetch()
+			}
 			return m, nil
 		}
 
+		if m.envPicker {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateEnvironmentPicker(msg.String()), nil
+		}
+
+		if m.comparePicker {
+			return m.updateComparePicker(msg.String())
+		}
+
 		if m.inputMode {
 			switch msg.String() {
 			case "enter":
 				aggregateID := strings.TrimSpace(m.textInput.Value())
 				if aggregateID == "" {
-					m.err = fmt.Errorf("aggregate ID cannot be empty")
+					m.err = fmt.Errorf("%s cannot be empty", lowerFirst(m.lookup.Label()))
 					return m, nil
 				}
-				if _, err := uuid.Parse(aggregateID); err != nil {
-					m.err = fmt.Errorf("invalid UUID format")
+				if err := fetcher.CheckID(m.services, m.lookup, aggregateID); err != nil {
+					m.err = err
 					return m, nil
 				}
 				m.err = nil
 				m.loading = true
-				m.initProgressSteps(false)
+				m.target = aggregateID
+				m.initProgressSteps(false, aggregateID)
 				m.loadingMsg = "Connecting to services..."
-				return m, tea.Batch(m.tickProgress(), m.loadFromServices(aggregateID))
+				ctx := m.startFetch()
+				return m, tea.Batch(waitForStep(m.steps, m.fetchID), m.loadFromServices(ctx, aggregateID, m.steps))
 			case "esc":
 				m.inputMode = false
 				m.textInput.Blur()
 				return m, nil
+			case "tab":
+				m = m.nextLookup()
+				return m, nil
 			}
 			var cmd tea.Cmd
 			m.textInput, cmd = m.textInput.Update(msg)
@@ -132,7 +191,7 @@ func (m EntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.menuSelection--
 			}
 		case "down", "j":
-			if m.menuSelection < optionMockMode {
+			if m.menuSelection < m.lastOption() {
 				m.menuSelection++
 			}
 		case "left", "h":
@@ -151,12 +210,17 @@ func (m EntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.previousIndex >= 0 && m.previousIndex < len(m.cache.Requests) {
 				// Load from cache
 				req := m.cache.Requests[m.previousIndex]
+				fetch := m.fetchID
 				return m, func() tea.Msg {
 					return LoadCompleteMsg{
+						Fetch:       fetch,
 						AggregateID: req.AggregateID,
 						Events:      req.Events,
 						Commands:    req.Commands,
 						IsMock:      req.IsMock,
+						Environment: req.Environment,
+						Lookup:      models.LookupKind(req.Lookup),
+						FromCache:   true,
 					}
 				}
 			}
@@ -167,9 +231,14 @@ func (m EntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, textinput.Blink
 			case optionMockMode:
 				m.loading = true
-				m.initProgressSteps(true)
+				m.initProgressSteps(true, "")
 				m.loadingMsg = "Connecting to mock services..."
-				return m, tea.Batch(m.tickProgress(), m.loadMockDataWithProgress())
+				ctx := m.startFetch()
+				return m, tea.Batch(waitForStep(m.steps, m.fetchID), m.loadMockDataWithProgress(ctx, m.steps))
+			case optionCompare:
+				return m.openComparePicker(), nil
+			case optionEnvironment:
+				return m.openEnvironmentPicker(), nil
 			}
 		}
 
@@ -192,31 +261,42 @@ func (m EntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 
 	case LoadCompleteMsg:
-		m.loading = false
-		// Save to cache
-		m.cache.AddRequest(msg.AggregateID, msg.Events, msg.Commands, msg.IsMock)
-		m.cache.Save()
+		if msg.Fetch != m.fetchID {
+			return m, nil
+		}
+		m.completeFetch(msg)
 		// Return the data view model
 		dataModel := NewModel(msg.AggregateID)
 		dataModel.Events = msg.Events
+		dataModel.Commands = msg.Commands
+		dataModel.Failures = msg.Failures
+		dataModel.Environment = msg.Environment
+		dataModel.Lookup = msg.Lookup
 		dataModel.Loading = false
-		dataModel.Services = m.services
+		dataModel.Config = m.cfg
 		return dataModel, func() tea.Msg {
 			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
 		}
 
 	case LoadErrorMsg:
+		// A cancelled fetch was already handled when Esc was pressed
+		if msg.Fetch != m.fetchID {
+			return m, nil
+		}
 		m.loading = false
+		m.cancel = nil
 		m.err = msg.Err
 
-	case ProgressMsg:
-		if m.currentStep < len(m.progressSteps) {
-			m.loadingMsg = m.progressSteps[m.currentStep]
-			m.progressPercent = float64(m.currentStep+1) / float64(len(m.progressSteps))
-			m.currentStep++
-			return m, m.tickProgress()
+	case FetchStepMsg:
+		// A cancelled fetch's chain ends here instead of reading the new channel
+		if msg.Fetch != m.fetchID {
+			return m, nil
 		}
-		return m, nil
+		m.applyStep(msg)
+		if len(msg.Events) > 0 || len(msg.Commands) > 0 {
+			return m.openStream(msg)
+		}
+		return m, tea.Batch(m.progress.SetPercent(m.progressPercent), waitForStep(m.steps, m.fetchID))
 
 	case progress.FrameMsg:
 		progressModel, cmd := m.progress.Update(msg)
@@ -227,38 +307,85 @@ func (m EntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m *EntryModel) initProgressSteps(isMock bool) {
-	var services []models.ServiceConfig
-	if isMock {
-		services = mock.MockServices
-	} else {
-		services = m.services
+// cancelFetch stops the running fetch and leaves the loading screen
+func (m *EntryModel) cancelFetch() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
 	}
+	m.fetchID++ // drop anything the cancelled fetch still sends
+	m.loading = false
+	m.err = fmt.Errorf("fetch cancelled")
+}
 
-	m.progressSteps = make([]string, 0)
-	for _, svc := range services {
-		m.progressSteps = append(m.progressSteps, fmt.Sprintf("Fetching events from %s...", svc.Name))
-		m.progressSteps = append(m.progressSteps, fmt.Sprintf("Fetching commands from %s...", svc.Name))
+// completeFetch ends the loading state and saves a fetched request to the
+// cache
+func (m *EntryModel) completeFetch(msg LoadCompleteMsg) {
+	m.loading = false
+	m.cancel = nil
+	if msg.FromCache {
+		return
 	}
-	m.currentStep = 0
-	m.progressPercent = 0
+	lookup := string(msg.Lookup)
+	if msg.Lookup == models.LookupAggregate {
+		lookup = ""
+	}
+	m.cache.AddRequest(msg.AggregateID, msg.Events, msg.Commands, msg.IsMock, msg.Environment, lookup)
+	m.cache.Save()
 }
 
-func (m EntryModel) tickProgress() tea.Cmd {
-	return tea.Tick(200*time.Millisecond, func(t time.Time) tea.Msg {
-		return ProgressMsg{}
+// openStream switches to the data view once the first rows arrive, leaving
+// the fetch running. The data view keeps the entry screen to follow the
+// fetch's progress and to return to if it is cancelled or fails.
+func (m EntryModel) openStream(first FetchStepMsg) (tea.Model, tea.Cmd) {
+	dataModel := NewModel(m.target)
+	dataModel.Environment = m.cfg.Environment
+	dataModel.Lookup = m.lookup
+	dataModel.Loading = false
+	dataModel.Config = m.cfg
+	dataModel.loader = &m
+	dataModel.appendPage(first)
+	return dataModel, tea.Batch(waitForStep(m.steps, m.fetchID), func() tea.Msg {
+		return tea.WindowSizeMsg{Width: m.width, Height: m.height}
 	})
 }
 
-func (m EntryModel) loadMockDataWithProgress() tea.Cmd {
+func (m EntryModel) loadMockDataWithProgress(ctx context.Context, steps chan<- FetchStepMsg) tea.Cmd {
 	return func() tea.Msg {
+		defer close(steps)
+
 		aggregateID := uuid.New().String()
 		events, commands := mock.GenerateMockData(aggregateID)
 
-		// Simulate network delay
-		time.Sleep(200 * time.Millisecond)
+		counts := make(map[string]int)
+		for _, e := range events {
+			counts[e.ServiceName+"/events"]++
+		}
+		for _, c := range commands {
+			counts[c.ServiceName+"/commands"]++
+		}
+
+		// Simulate network delay, reporting each mock call as it "completes"
+		for _, svc := range mock.MockServices {
+			for _, stepType := range []string{"events", "commands"} {
+				latency := 40 * time.Millisecond
+				select {
+				case <-ctx.Done():
+					return LoadErrorMsg{Fetch: m.fetchID, Err: ctx.Err()}
+				case <-time.After(latency):
+				}
+				sendStep(ctx, steps, FetchStepMsg{
+					ServiceName: svc.Name,
+					StepType:    stepType,
+					Done:        true,
+					Items:       counts[svc.Name+"/"+stepType],
+					Latency:     latency,
+				})
+			}
+		}
 
 		return LoadCompleteMsg{
+			Fetch:       m.fetchID,
 			AggregateID: aggregateID,
 			Events:      events,
 			Commands:    commands,
@@ -267,23 +394,39 @@ func (m EntryModel) loadMockDataWithProgress() tea.Cmd {
 	}
 }
 
-func (m EntryModel) loadFromServices(aggregateID string) tea.Cmd {
+func (m EntryModel) loadFromServices(ctx context.Context, aggregateID string, steps chan<- FetchStepMsg) tea.Cmd {
 	return func() tea.Msg {
+		defer close(steps)
+
 		if len(m.services) == 0 {
-			return LoadErrorMsg{Err: fmt.Errorf("no services configured. Set DRILL_SERVICES env var")}
+			return LoadErrorMsg{Fetch: m.fetchID, Err: fmt.Errorf("no services configured. Create a .drill.yaml file")}
 		}
 
 		f := fetcher.NewFetcher(m.services)
-		events, commands, err := f.FetchAll(aggregateID)
+		f.SetRetryPolicy(m.cfg.Retry)
+		f.OnResult(func(r fetcher.FetchResult) {
+			sendStep(ctx, steps, stepFromResult(r, true))
+		})
+		f.OnPage(func(r fetcher.FetchResult) {
+			sendStep(ctx, steps, stepFromResult(r, false))
+		})
+		f.OnRelated(func(aggregates []string) {
+			sendStep(ctx, steps, FetchStepMsg{Related: aggregates})
+		})
+		report, err := f.FetchRelated(ctx, m.lookup, aggregateID)
 		if err != nil {
-			return LoadErrorMsg{Err: err}
+			return LoadErrorMsg{Fetch: m.fetchID, Err: err}
 		}
 
 		return LoadCompleteMsg{
+			Fetch:       m.fetchID,
 			AggregateID: aggregateID,
-			Events:      events,
-			Commands:    commands,
+			Events:      report.Events,
+			Commands:    report.Commands,
+			Failures:    report.Failures(),
 			IsMock:      false,
+			Environment: m.cfg.Environment,
+			Lookup:      m.lookup,
 		}
 	}
 }
@@ -325,7 +468,7 @@ func (m EntryModel) View() string {
 		rightStyle.Render(rightPanel),
 	)
 
-	title := TitleStyle.Render("Drill - Event Source Debugger")
+	title := renderTitle("Drill - Event Source Debugger", m.cfg.ActiveEnvironment())
 
 	help := HelpStyle.Render("Tab/Arrows: navigate | Enter: select | q: quit")
 
@@ -333,7 +476,7 @@ func (m EntryModel) View() string {
 }
 
 func (m EntryModel) renderLoading() string {
-	title := TitleStyle.Render("Drill - Event Source Debugger")
+	title := renderTitle("Drill - Event Source Debugger", m.cfg.ActiveEnvironment())
 
 	// Progress content
 	var content strings.Builder
@@ -341,11 +484,14 @@ func (m EntryModel) renderLoading() string {
 	content.WriteString("\n\n")
 	content.WriteString(m.loadingMsg)
 	content.WriteString("\n\n")
-	content.WriteString(m.progress.ViewAs(m.progressPercent))
+	content.WriteString(m.progress.View())
 	content.WriteString("\n\n")
 
-	stepInfo := fmt.Sprintf("Step %d of %d", m.currentStep, len(m.progressSteps))
+	stepInfo := fmt.Sprintf("%d of %d calls complete", m.currentStep, len(m.progressSteps))
 	content.WriteString(HelpStyle.Render(stepInfo))
+	content.WriteString("\n\n")
+	content.WriteString(m.renderSteps())
+	content.WriteString(HelpStyle.Render("Esc: cancel | ctrl+c: quit"))
 
 	contentStyle := lipgloss.NewStyle().
 		Width(m.width).
@@ -368,8 +514,12 @@ func (m EntryModel) renderMenu() string {
 	sb.WriteString("\n\n")
 
 	options := []string{
-		"Load Account (Enter UUID)",
+		"Load Account (Enter ID)",
 		"Run Mock Mode",
+		"Compare Cached Requests",
+	}
+	if env := m.cfg.ActiveEnvironment(); env != nil {
+		options = append(options, fmt.Sprintf("Environment: %s", env.Name))
 	}
 
 	for i, opt := range options {
@@ -388,7 +538,23 @@ func (m EntryModel) renderMenu() string {
 		sb.WriteString("\n")
 		sb.WriteString(m.textInput.View())
 		sb.WriteString("\n")
-		sb.WriteString(HelpStyle.Render("Press Enter to submit, Esc to cancel"))
+		sb.WriteString(m.renderLookup())
+		sb.WriteString("\n")
+		help := "Press Enter to submit, Esc to cancel"
+		if len(m.lookupKinds()) > 1 {
+			help = "Press Enter to submit, Tab to change ID type, Esc to cancel"
+		}
+		sb.WriteString(HelpStyle.Render(help))
+	}
+
+	if m.envPicker {
+		sb.WriteString("\n")
+		sb.WriteString(m.renderEnvironmentPicker())
+	}
+
+	if m.comparePicker {
+		sb.WriteString("\n")
+		sb.WriteString(m.renderComparePicker())
 	}
 
 	if m.err != nil {
@@ -426,20 +592,19 @@ func (m EntryModel) renderPreviousRequests() string {
 				Bold(true)
 		}
 
-		mockLabel := ""
-		if req.IsMock {
-			mockLabel = " [MOCK]"
-		}
-
-		line := fmt.Sprintf("%s%s", req.AggregateID, mockLabel)
-		sb.WriteString(style.Render(line))
+		sb.WriteString(style.Render(requestLabel(req)))
 		sb.WriteString("\n")
 
 		// Show timestamp and counts
-		details := HelpStyle.Render(fmt.Sprintf("  %s | %d cmds, %d events",
+		snapshots := ""
+		if len(req.Previous) > 0 {
+			snapshots = fmt.Sprintf(" | %d earlier", len(req.Previous))
+		}
+		details := HelpStyle.Render(fmt.Sprintf("  %s | %d cmds, %d events%s",
 			req.Timestamp.Format("Jan 02 15:04"),
 			len(req.Commands),
 			len(req.Events),
+			snapshots,
 		))
 		sb.WriteString(details)
 		sb.WriteString("\n")
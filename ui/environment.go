@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"drill/config"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderTitle renders the title bar, with a badge naming the environment
+// when one is selected
+func renderTitle(text string, env *config.Environment) string {
+	title := TitleStyle.Render(text)
+	if env == nil {
+		return title
+	}
+
+	badge := EnvironmentStyle.Render(env.Name)
+	if env.Production {
+		badge = ProductionStyle.Render("⚠ " + strings.ToUpper(env.Name))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, title, " ", badge)
+}
+
+// lastOption returns the bottom entry of the menu, which only offers the
+// environment picker when environments are configured
+func (m EntryModel) lastOption() menuOption {
+	if len(m.cfg.Environments) > 0 {
+		return optionEnvironment
+	}
+	return optionCompare
+}
+
+// updateEnvironmentPicker handles keys while the environment list is open
+func (m EntryModel) updateEnvironmentPicker(key string) EntryModel {
+	switch key {
+	case "up", "k":
+		if m.envIndex > 0 {
+			m.envIndex--
+		}
+	case "down", "j":
+		if m.envIndex < len(m.cfg.Environments)-1 {
+			m.envIndex++
+		}
+	case "enter":
+		if err := m.cfg.UseEnvironment(m.cfg.Environments[m.envIndex].Name); err != nil {
+			m.err = err
+		} else {
+			m.services = m.cfg.Services
+			m.err = nil
+		}
+		m.envPicker = false
+	case "esc":
+		m.envPicker = false
+	}
+	return m
+}
+
+// openEnvironmentPicker shows the environment list with the active one
+// highlighted
+func (m EntryModel) openEnvironmentPicker() EntryModel {
+	m.envPicker = true
+	m.envIndex = 0
+	for i, env := range m.cfg.Environments {
+		if env.Name == m.cfg.Environment {
+			m.envIndex = i
+		}
+	}
+	return m
+}
+
+func (m EntryModel) renderEnvironmentPicker() string {
+	var sb strings.Builder
+
+	for i, env := range m.cfg.Environments {
+		style := lipgloss.NewStyle().Padding(0, 2)
+		if env.Production {
+			style = style.Foreground(ProductionStyle.GetBackground())
+		}
+		if i == m.envIndex {
+			style = style.
+				Background(lipgloss.Color("#5c6bc0")).
+				Foreground(lipgloss.Color("#ffffff")).
+				Bold(true)
+		}
+
+		label := env.Name
+		if env.Name == m.cfg.Environment {
+			label += " (current)"
+		}
+		sb.WriteString(style.Render(label))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(HelpStyle.Render("Press Enter to switch, Esc to cancel"))
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// serviceFacet is one service in the loaded data with its row counts
+type serviceFacet struct {
+	name     string
+	events   int
+	commands int
+}
+
+// serviceFacets lists every service in the loaded data, ignoring the search
+// and hidden services, ordered by name
+func (m Model) serviceFacets() []serviceFacet {
+	counts := make(map[string]*serviceFacet)
+	facet := func(name string) *serviceFacet {
+		if counts[name] == nil {
+			counts[name] = &serviceFacet{name: name}
+		}
+		return counts[name]
+	}
+	for _, e := range m.allEvents {
+		facet(e.ServiceName).events++
+	}
+	for _, c := range m.allCommands {
+		facet(c.ServiceName).commands++
+	}
+
+	facets := make([]serviceFacet, 0, len(counts))
+	for _, f := range counts {
+		facets = append(facets, *f)
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		return facets[i].name < facets[j].name
+	})
+	return facets
+}
+
+// updateFacets handles keys while the services panel is open
+func (m Model) updateFacets(msg tea.KeyMsg) (Model, tea.Cmd) {
+	facets := m.serviceFacets()
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc", "s":
+		m.facetsOpen = false
+		m.updateDetailView()
+		return m, nil
+	case "up", "k":
+		if m.facetIndex > 0 {
+			m.facetIndex--
+		}
+	case "down", "j":
+		if m.facetIndex < len(facets)-1 {
+			m.facetIndex++
+		}
+	case "enter", " ":
+		if len(facets) > 0 {
+			name := facets[m.facetIndex].name
+			m.hiddenServices[name] = !m.hiddenServices[name]
+			m.applyServices()
+		}
+	case "o":
+		// Show only the highlighted service
+		for i, f := range facets {
+			m.hiddenServices[f.name] = i != m.facetIndex
+		}
+		m.applyServices()
+	case "a":
+		m.hiddenServices = make(map[string]bool)
+		m.applyServices()
+	}
+
+	m.updateDetailView()
+	return m, nil
+}
+
+// applyServices refilters after services were shown or hidden
+func (m *Model) applyServices() {
+	m.selectedIndex = 0
+	m.applyFilter()
+	m.updateListView()
+}
+
+// hiddenCount returns how many services in the loaded data are hidden
+func (m Model) hiddenCount() int {
+	n := 0
+	for _, f := range m.serviceFacets() {
+		if m.hiddenServices[f.name] {
+			n++
+		}
+	}
+	return n
+}
+
+// openFacets shows the services panel in place of the detail pane
+func (m *Model) openFacets() {
+	m.facetsOpen = true
+	if m.hiddenServices == nil {
+		m.hiddenServices = make(map[string]bool)
+	}
+	if m.facetIndex >= len(m.serviceFacets()) {
+		m.facetIndex = 0
+	}
+	m.updateDetailView()
+}
+
+// renderFacets lists the services with a checkbox and counts, each name in
+// the colour used for it in the tables so the panel doubles as a legend
+func (m Model) renderFacets() string {
+	facets := m.serviceFacets()
+	if len(facets) == 0 {
+		return "No services in the loaded data"
+	}
+
+	nameWidth := 0
+	for _, f := range facets {
+		nameWidth = max(nameWidth, len(f.name))
+	}
+
+	var sb strings.Builder
+	for i, f := range facets {
+		check, swatch, name := "[x]", CreateServiceStyle(f.name).Render("■"), CreateServiceStyle(f.name).Render(f.name)
+		if m.hiddenServices[f.name] {
+			check, swatch, name = "[ ]", HelpStyle.UnsetMarginTop().Render("□"), HelpStyle.UnsetMarginTop().Render(f.name)
+		}
+		padding := strings.Repeat(" ", nameWidth-len(f.name))
+		row := fmt.Sprintf("%s %s %s%s  %3d events  %3d commands", check, swatch, name, padding, f.events, f.commands)
+		if i == m.facetIndex {
+			row = lipgloss.NewStyle().
+				Background(lipgloss.Color("#5c6bc0")).
+				Render(row)
+		}
+		sb.WriteString(row)
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(HelpStyle.UnsetMarginTop().Render("Space: toggle | o: only this service | a: show all | s/Esc: close"))
+	return sb.String()
+}
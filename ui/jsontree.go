@@ -0,0 +1,328 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type jsonKind int
+
+const (
+	jsonObject jsonKind = iota
+	jsonArray
+	jsonString
+	jsonNumber
+	jsonBool
+	jsonNull
+	jsonRaw // payload that is not JSON, shown as is
+)
+
+// jsonNode is one value in a payload, with object keys in document order
+type jsonNode struct {
+	id       int    // preorder position, keys the collapsed state
+	key      string // object key, empty for array items and the root
+	keyed    bool   // key is set, even if empty
+	kind     jsonKind
+	value    string // scalar text, for non-containers
+	children []*jsonNode
+	depth    int
+}
+
+const (
+	// Containers with more children than this start collapsed
+	collapseChildren = 50
+	// Payloads with more nodes than this start collapsed below the top level
+	collapseNodes = 1000
+	// String values longer than this are shortened for display
+	maxStringRunes = 300
+	// Visible lines are capped so a fully expanded payload stays responsive
+	maxTreeLines = 5000
+)
+
+// jsonTree is a collapsible view of a payload with a cursor over its
+// visible lines
+type jsonTree struct {
+	root      *jsonNode
+	count     int
+	collapsed map[int]bool
+	cursor    int
+}
+
+// treeLine is one visible line: a scalar, a collapsed container, or the
+// opening or closing bracket of an expanded one
+type treeLine struct {
+	node    *jsonNode
+	closing bool
+	last    bool // no comma follows
+}
+
+// newJSONTree parses a payload, keeping key order. Anything that is not a
+// single JSON value is shown as raw text.
+func newJSONTree(payload string) *jsonTree {
+	t := &jsonTree{collapsed: make(map[int]bool)}
+
+	dec := json.NewDecoder(strings.NewReader(payload))
+	dec.UseNumber()
+	root, err := t.parse(dec, 0)
+	if err == nil {
+		if _, err = dec.Token(); !errors.Is(err, io.EOF) {
+			err = fmt.Errorf("trailing data")
+		} else {
+			err = nil
+		}
+	}
+	if err != nil {
+		t.count = 1
+		root = &jsonNode{kind: jsonRaw, value: payload}
+	}
+	t.root = root
+
+	t.walk(root, func(n *jsonNode) {
+		if n.depth > 0 && (len(n.children) > collapseChildren || t.count > collapseNodes && len(n.children) > 0) {
+			t.collapsed[n.id] = true
+		}
+	})
+	return t
+}
+
+func (t *jsonTree) parse(dec *json.Decoder, depth int) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &jsonNode{id: t.count, depth: depth}
+	t.count++
+
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			n.kind = jsonObject
+			n.children = []*jsonNode{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				child, err := t.parse(dec, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				child.key, child.keyed = keyTok.(string), true
+				n.children = append(n.children, child)
+			}
+		case '[':
+			n.kind = jsonArray
+			n.children = []*jsonNode{}
+			for dec.More() {
+				child, err := t.parse(dec, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, child)
+			}
+		}
+		if _, err := dec.Token(); err != nil { // closing bracket
+			return nil, err
+		}
+	case string:
+		n.kind, n.value = jsonString, v
+	case json.Number:
+		n.kind, n.value = jsonNumber, v.String()
+	case bool:
+		n.kind, n.value = jsonBool, strconv.FormatBool(v)
+	case nil:
+		n.kind, n.value = jsonNull, "null"
+	}
+	return n, nil
+}
+
+func (t *jsonTree) walk(n *jsonNode, fn func(*jsonNode)) {
+	fn(n)
+	for _, c := range n.children {
+		t.walk(c, fn)
+	}
+}
+
+// lines flattens the visible part of the tree
+func (t *jsonTree) lines() []treeLine {
+	var out []treeLine
+	var visit func(n *jsonNode, last bool)
+	visit = func(n *jsonNode, last bool) {
+		if len(out) >= maxTreeLines {
+			return
+		}
+		out = append(out, treeLine{node: n, last: last})
+		if len(n.children) == 0 || t.collapsed[n.id] {
+			return
+		}
+		for i, c := range n.children {
+			visit(c, i == len(n.children)-1)
+		}
+		out = append(out, treeLine{node: n, closing: true, last: last})
+	}
+	visit(t.root, true)
+	return out
+}
+
+// move shifts the cursor by delta visible lines
+func (t *jsonTree) move(delta int) {
+	t.cursor = max(0, min(t.cursor+delta, len(t.lines())-1))
+}
+
+// setCollapsed collapses or expands the container under the cursor. On a
+// scalar, collapsing moves to the parent container instead.
+func (t *jsonTree) setCollapsed(collapsed bool) {
+	lines := t.lines()
+	if t.cursor >= len(lines) {
+		return
+	}
+	n := lines[t.cursor].node
+	if len(n.children) == 0 {
+		if collapsed {
+			t.cursor = t.parentLine(lines, t.cursor)
+		}
+		return
+	}
+	t.collapsed[n.id] = collapsed
+	if collapsed && lines[t.cursor].closing {
+		t.cursor = t.openingLine(lines, n)
+	}
+}
+
+// toggle flips the container under the cursor
+func (t *jsonTree) toggle() {
+	lines := t.lines()
+	if t.cursor < len(lines) {
+		t.setCollapsed(!t.collapsed[lines[t.cursor].node.id])
+	}
+}
+
+// setAll expands or collapses every container below the root
+func (t *jsonTree) setAll(collapsed bool) {
+	t.walk(t.root, func(n *jsonNode) {
+		if n != t.root && len(n.children) > 0 {
+			t.collapsed[n.id] = collapsed
+		}
+	})
+	t.move(0)
+}
+
+func (t *jsonTree) openingLine(lines []treeLine, n *jsonNode) int {
+	for i, l := range lines {
+		if l.node == n && !l.closing {
+			return i
+		}
+	}
+	return 0
+}
+
+// parentLine returns the opening line of the container enclosing line i
+func (t *jsonTree) parentLine(lines []treeLine, i int) int {
+	depth := lines[i].node.depth
+	for j := i - 1; j >= 0; j-- {
+		if !lines[j].closing && lines[j].node.depth < depth {
+			return j
+		}
+	}
+	return i
+}
+
+// render draws the visible lines, highlighting the cursor line when
+// cursor is true
+func (t *jsonTree) render(cursor bool) string {
+	if t.root.kind == jsonRaw {
+		if t.root.value == "" {
+			return HelpStyle.Render("(empty)")
+		}
+		return JSONPunctStyle.Render(t.root.value)
+	}
+
+	lines := t.lines()
+	var sb strings.Builder
+	for i, l := range lines {
+		text := t.renderLine(l)
+		if cursor && i == t.cursor {
+			text = SelectedRowStyle.Render("▸") + text
+		} else if cursor {
+			text = " " + text
+		}
+		sb.WriteString(text)
+		sb.WriteString("\n")
+	}
+	if len(lines) >= maxTreeLines {
+		sb.WriteString(HelpStyle.UnsetMarginTop().Render(fmt.Sprintf("… stopped at %d lines, collapse nodes to see the rest", maxTreeLines)))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (t *jsonTree) renderLine(l treeLine) string {
+	n := l.node
+	comma := ""
+	if !l.last {
+		comma = JSONPunctStyle.Render(",")
+	}
+	indent := strings.Repeat("  ", n.depth)
+
+	if l.closing {
+		return indent + JSONPunctStyle.Render(closeBracket(n.kind)) + comma
+	}
+
+	prefix := indent
+	if n.keyed {
+		prefix += JSONKeyStyle.Render(strconv.Quote(n.key)) + JSONPunctStyle.Render(": ")
+	}
+
+	switch n.kind {
+	case jsonObject, jsonArray:
+		opening, closing := openBracket(n.kind), closeBracket(n.kind)
+		switch {
+		case len(n.children) == 0:
+			return prefix + JSONPunctStyle.Render(opening+closing) + comma
+		case t.collapsed[n.id]:
+			unit := "keys"
+			if n.kind == jsonArray {
+				unit = "items"
+			}
+			return prefix + JSONPunctStyle.Render(opening+"…"+closing) + comma +
+				HelpStyle.UnsetMarginTop().Render(fmt.Sprintf(" %d %s", len(n.children), unit))
+		}
+		return prefix + JSONPunctStyle.Render(opening)
+	case jsonString:
+		return prefix + JSONStringStyle.Render(quoteShort(n.value)) + comma
+	case jsonNumber:
+		return prefix + JSONNumberStyle.Render(n.value) + comma
+	case jsonBool:
+		return prefix + JSONBoolStyle.Render(n.value) + comma
+	}
+	return prefix + JSONNullStyle.Render(n.value) + comma
+}
+
+// quoteShort quotes a string value, shortening long ones
+func quoteShort(s string) string {
+	if utf8.RuneCountInString(s) <= maxStringRunes {
+		return strconv.Quote(s)
+	}
+	runes := []rune(s)
+	return strconv.Quote(string(runes[:maxStringRunes])) + fmt.Sprintf("… (%d chars)", len(runes))
+}
+
+func openBracket(k jsonKind) string {
+	if k == jsonArray {
+		return "["
+	}
+	return "{"
+}
+
+func closeBracket(k jsonKind) string {
+	if k == jsonArray {
+		return "]"
+	}
+	return "}"
+}
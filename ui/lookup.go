@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"drill/fetcher"
+	"drill/models"
+	"fmt"
+	"strings"
+)
+
+// lookupKinds returns the kinds of ID that at least one configured service
+// can be queried by, always offering aggregate lookups first
+func (m EntryModel) lookupKinds() []models.LookupKind {
+	kinds := []models.LookupKind{models.LookupAggregate}
+	for _, kind := range models.LookupKinds[1:] {
+		if len(fetcher.SupportedServices(m.services, kind)) > 0 {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// nextLookup cycles the kind of ID being entered
+func (m EntryModel) nextLookup() EntryModel {
+	kinds := m.lookupKinds()
+	for i, kind := range kinds {
+		if kind == m.lookup {
+			m.lookup = kinds[(i+1)%len(kinds)]
+			break
+		}
+	}
+	m.textInput.Placeholder = fmt.Sprintf("Enter %s", m.lookup.Label())
+	m.err = nil
+	return m
+}
+
+// renderLookup shows the kind of ID being entered and how many services
+// will be queried for it, counting only those whose ID format accepts the
+// value typed so far
+func (m EntryModel) renderLookup() string {
+	supported := len(fetcher.SupportedServices(m.services, m.lookup))
+	if id := strings.TrimSpace(m.textInput.Value()); id != "" {
+		supported = len(fetcher.QueryableServices(m.services, m.lookup, id))
+	}
+	return HelpStyle.Render(fmt.Sprintf("Looking up by %s, querying %d of %d services",
+		m.lookup.Label(), supported, len(m.services)))
+}
+
+// lowerFirst lowercases the first letter, turning a label such as
+// "Aggregate ID" into "aggregate ID" for use mid-sentence
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -1,29 +1,91 @@
 package ui
 
 import (
+	"drill/config"
+	"drill/fetcher"
 	"drill/models"
-	"encoding/json"
+	"drill/projection"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+type viewMode int
+
+const (
+	modeEvents viewMode = iota
+	modeCommands
+	modeTimeline
+	modeCorrelation
+	modeCount
+)
+
 type Model struct {
-	Events         []models.Event
-	eventsViewport viewport.Model
-	detailViewport viewport.Model
-	selectedIndex  int
-	width          int
-	height         int
-	ready          bool
-	aggregateID    string
-	Loading        bool
-	err            error
-	Services       []models.ServiceConfig
+	Events   []models.Event
+	Commands []models.Command
+	timeline []timelineEntry
+	// Correlation tree state
+	correlationGroups []correlationGroup
+	treeRows          []treeRow
+	collapsed         map[string]bool
+	mode              viewMode
+	eventsViewport    viewport.Model
+	detailViewport    viewport.Model
+	selectedIndex     int
+	width             int
+	height            int
+	ready             bool
+	aggregateID       string
+	Loading           bool
+	err               error
+	Config            *config.Config
+	Failures          []fetcher.FetchResult
+	Environment       string            // environment the data was fetched from
+	Lookup            models.LookupKind // kind of ID the data was looked up by
+
+	// Fetch still streaming in. loader is the entry screen that started it,
+	// nil once it has finished, and streamed the rows a related lookup has
+	// already shown.
+	loader   *EntryModel
+	streamed map[string]bool
+
+	// Search state. Events and Commands hold the rows matching filter;
+	// eventPos[i] is the position of Events[i] in allEvents.
+	allEvents   []models.Event
+	allCommands []models.Command
+	eventPos    []int
+	filter      string
+	filterErr   error // set when filter looks like an expression but does not parse
+	searching   bool
+	searchInput textinput.Model
+
+	// Services panel state. Rows from hidden services are left out of
+	// Events and Commands alongside the search filter.
+	facetsOpen     bool
+	facetIndex     int
+	hiddenServices map[string]bool
+
+	// Reconstructed aggregate state, folded from allEvents
+	replay    *projection.Replay
+	replayErr error
+
+	// Payload tree of the selected row. treeKey identifies the row so the
+	// collapsed state survives redraws; treeFocus sends keys to the tree.
+	tree      *jsonTree
+	treeKey   string
+	treeFocus bool
+
+	// Payload diff state. diffBase is the event pinned to diff against,
+	// nil to use the previous event with the same alias, and diffBasePos
+	// its position in allEvents.
+	payloadDiff bool
+	diffBase    *models.Event
+	diffBasePos int
 }
 
 type DataLoadedMsg struct {
@@ -50,26 +112,92 @@ func (m Model) Init() tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		if m.facetsOpen {
+			return m.updateFacets(msg)
+		}
+		if m.treeFocus {
+			return m.updatePayloadTree(msg)
+		}
+
 		switch msg.String() {
 		case "q", "ctrl+c":
+			if m.loader != nil && m.loader.cancel != nil {
+				m.loader.cancel()
+			}
 			return m, tea.Quit
+		case "/":
+			return m, m.startSearch()
+		case "s":
+			m.openFacets()
+		case "d":
+			m.payloadDiff = !m.payloadDiff
+			m.updateDetailView()
+		case "b":
+			m.toggleDiffBase()
+			m.updateDetailView()
+		case "p":
+			if m.tree != nil {
+				m.treeFocus = true
+				m.updateDetailView()
+			}
 		case "esc":
+			if m.filter != "" {
+				m.filter = ""
+				m.applyFilter()
+				m.updateDetailView()
+				m.updateListView()
+				return m, nil
+			}
+			if m.loader != nil {
+				return m.cancelStream()
+			}
 			// Go back to entry screen
-			entry := NewEntryModel(m.Services)
+			entry := NewEntryModel(m.Config)
 			return entry, func() tea.Msg {
 				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
 			}
+		case "tab":
+			m.mode = (m.mode + 1) % modeCount
+			m.selectedIndex = 0
+			m.updateDetailView()
+			m.updateListView()
+		case "shift+tab":
+			m.mode = (m.mode + modeCount - 1) % modeCount
+			m.selectedIndex = 0
+			m.updateDetailView()
+			m.updateListView()
+		case "enter", " ":
+			if m.mode == modeCorrelation {
+				m.toggleCollapsed()
+				m.updateDetailView()
+				m.updateListView()
+			}
+		case "left", "h":
+			if m.mode == modeCorrelation {
+				m.setCollapsed(true)
+				m.updateDetailView()
+				m.updateListView()
+			}
+		case "right", "l":
+			if m.mode == modeCorrelation {
+				m.setCollapsed(false)
+				m.updateDetailView()
+				m.updateListView()
+			}
 		case "up", "k":
 			if m.selectedIndex > 0 {
 				m.selectedIndex--
 				m.updateDetailView()
-				m.updateEventsView()
+				m.updateListView()
 			}
 		case "down", "j":
-			if m.selectedIndex < len(m.Events)-1 {
+			if m.selectedIndex < m.rowCount()-1 {
 				m.selectedIndex++
 				m.updateDetailView()
-				m.updateEventsView()
+				m.updateListView()
 			}
 		case "pgup":
 			m.selectedIndex -= 10
@@ -77,29 +205,35 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selectedIndex = 0
 			}
 			m.updateDetailView()
-			m.updateEventsView()
+			m.updateListView()
 		case "pgdown":
 			m.selectedIndex += 10
-			if m.selectedIndex >= len(m.Events) {
-				m.selectedIndex = len(m.Events) - 1
+			if m.selectedIndex >= m.rowCount() {
+				m.selectedIndex = m.rowCount() - 1
+			}
+			if m.selectedIndex < 0 {
+				m.selectedIndex = 0
 			}
 			m.updateDetailView()
-			m.updateEventsView()
+			m.updateListView()
 		case "home", "g":
 			m.selectedIndex = 0
 			m.updateDetailView()
-			m.updateEventsView()
+			m.updateListView()
 		case "end", "G":
-			m.selectedIndex = len(m.Events) - 1
+			m.selectedIndex = m.rowCount() - 1
+			if m.selectedIndex < 0 {
+				m.selectedIndex = 0
+			}
 			m.updateDetailView()
-			m.updateEventsView()
+			m.updateListView()
 		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 
-		headerHeight := 4
+		headerHeight := 4 + m.bannerHeight()
 		footerHeight := 3
 		availableHeight := m.height - headerHeight - footerHeight
 
@@ -112,9 +246,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.ready = true
 
 			// Sort data (for data loaded from entry screen)
-			sort.Slice(m.Events, func(i, j int) bool {
-				return m.Events[i].Metadata.PersistedAt.Before(m.Events[j].Metadata.PersistedAt)
-			})
+			m.prepareData()
 		} else {
 			m.eventsViewport.Width = leftWidth
 			m.eventsViewport.Height = availableHeight
@@ -122,24 +254,26 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.detailViewport.Height = availableHeight
 		}
 
-		m.updateEventsView()
+		m.updateListView()
 		m.updateDetailView()
 
 	case DataLoadedMsg:
 		m.Loading = false
 		m.Events = msg.Events
+		m.Commands = msg.Commands
 
 		// Sort by persistedAt
-		sort.Slice(m.Events, func(i, j int) bool {
-			return m.Events[i].Metadata.PersistedAt.Before(m.Events[j].Metadata.PersistedAt)
-		})
+		m.prepareData()
 
-		m.updateEventsView()
+		m.updateListView()
 		m.updateDetailView()
 
 	case ErrorMsg:
 		m.Loading = false
 		m.err = msg.Err
+
+	case FetchStepMsg, LoadCompleteMsg, LoadErrorMsg:
+		return m.updateStream(msg)
 	}
 
 	// Handle viewport scrolling for detail panel
@@ -149,8 +283,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *Model) updateEventsView() {
-	m.eventsViewport.SetContent(m.renderEvents())
+// prepareData orders events and commands by persistedAt and rebuilds the
+// merged timeline, keeping any search filter
+func (m *Model) prepareData() {
+	// Stable, so rows with the same time keep their order as pages stream in
+	sort.SliceStable(m.Events, func(i, j int) bool {
+		return m.Events[i].Metadata.PersistedAt.Before(m.Events[j].Metadata.PersistedAt)
+	})
+	sort.SliceStable(m.Commands, func(i, j int) bool {
+		return m.Commands[i].PersistedAt.Before(m.Commands[j].PersistedAt)
+	})
+	m.allEvents, m.allCommands = m.Events, m.Commands
+	m.replay, m.replayErr = m.newReplay()
+	m.applyFilter()
+}
+
+// rowCount returns the number of selectable rows in the current mode
+func (m Model) rowCount() int {
+	switch m.mode {
+	case modeCommands:
+		return len(m.Commands)
+	case modeTimeline:
+		return len(m.timeline)
+	case modeCorrelation:
+		return len(m.treeRows)
+	default:
+		return len(m.Events)
+	}
+}
+
+func (m *Model) updateListView() {
+	switch m.mode {
+	case modeCommands:
+		m.eventsViewport.SetContent(m.renderCommands())
+	case modeTimeline:
+		m.eventsViewport.SetContent(m.renderTimeline())
+	case modeCorrelation:
+		m.eventsViewport.SetContent(m.renderCorrelationTree())
+	default:
+		m.eventsViewport.SetContent(m.renderEvents())
+	}
 
 	// Auto-scroll to keep selected item visible
 	visibleLines := m.eventsViewport.Height - 2 // account for header
@@ -162,7 +334,27 @@ func (m *Model) updateEventsView() {
 }
 
 func (m *Model) updateDetailView() {
-	m.detailViewport.SetContent(m.renderEventDetail())
+	if m.facetsOpen {
+		m.detailViewport.SetContent(m.renderFacets())
+		return
+	}
+
+	m.syncTree()
+	if m.treeFocus {
+		m.showTreeCursor()
+		return
+	}
+
+	switch m.mode {
+	case modeCommands:
+		m.detailViewport.SetContent(m.renderCommandDetail())
+	case modeTimeline:
+		m.detailViewport.SetContent(m.renderTimelineDetail())
+	case modeCorrelation:
+		m.detailViewport.SetContent(m.renderCorrelationDetail())
+	default:
+		m.detailViewport.SetContent(m.renderEventDetail())
+	}
 	m.detailViewport.GotoTop()
 }
 
@@ -231,8 +423,12 @@ func (m Model) renderEventDetail() string {
 		return "No event selected"
 	}
 
-	evt := m.Events[m.selectedIndex]
+	return m.eventDetail(&m.Events[m.selectedIndex])
+}
 
+// formatEventDetail renders the detail pane contents for a single event,
+// ending with the already rendered payload
+func formatEventDetail(evt models.Event, payload string) string {
 	var sb strings.Builder
 
 	// Title
@@ -277,23 +473,39 @@ func (m Model) renderEventDetail() string {
 	sb.WriteString("\n\n")
 
 	// Payload
-	sb.WriteString(labelStyle.Render("Payload:"))
+	sb.WriteString(payloadHeading(evt.PayloadDecoding, evt.PayloadError, labelStyle))
 	sb.WriteString("\n")
+	sb.WriteString(payload)
+	sb.WriteString(storedPayload(evt.RawPayload, labelStyle))
 
-	// Pretty print JSON payload
-	if evt.Payload != "" {
-		var prettyJSON map[string]interface{}
-		if err := json.Unmarshal([]byte(evt.Payload), &prettyJSON); err == nil {
-			formatted, _ := json.MarshalIndent(prettyJSON, "", "  ")
-			sb.WriteString(valueStyle.Render(string(formatted)))
-		} else {
-			sb.WriteString(valueStyle.Render(evt.Payload))
-		}
-	} else {
-		sb.WriteString(HelpStyle.Render("(empty)"))
+	return sb.String()
+}
+
+// payloadHeading labels a payload with how it was decoded from its stored
+// form, or why it could not be
+func payloadHeading(decoding, decodeErr string, labelStyle lipgloss.Style) string {
+	switch {
+	case decodeErr != "":
+		return labelStyle.Render("Payload:") + "\n" + FailedCommandStyle.Render("Shown as stored, could not decode: "+decodeErr)
+	case decoding != "":
+		return labelStyle.Render("Payload:") + " " + HelpStyle.UnsetMarginTop().Render("(decoded: "+decoding+")")
 	}
+	return labelStyle.Render("Payload:")
+}
 
-	return sb.String()
+// storedPayload shows the payload as stored when it was decoded for
+// display, and nothing otherwise
+func storedPayload(raw string, labelStyle lipgloss.Style) string {
+	if raw == "" {
+		return ""
+	}
+	return "\n\n" + labelStyle.Render("Stored payload:") + "\n" + raw
+}
+
+// renderPayload pretty prints a payload as a JSON tree, falling back to the
+// raw string
+func renderPayload(payload string) string {
+	return newJSONTree(payload).render(false)
 }
 
 func (m Model) View() string {
@@ -310,14 +522,34 @@ func (m Model) View() string {
 	}
 
 	// Title
-	title := TitleStyle.Render(fmt.Sprintf("Event Debugger - Aggregate: %s", m.aggregateID))
+	title := renderTitle(m.titleText(), m.environment())
+
+	// Partial failure banner
+	if banner := m.renderBanner(); banner != "" {
+		title = lipgloss.JoinVertical(lipgloss.Left, title, banner)
+	}
 
 	// Create panel headers
 	leftWidth := m.width / 2
 	rightWidth := m.width - leftWidth - 3
 
-	eventsHeader := HeaderStyle.Width(leftWidth).Align(lipgloss.Center).Render("EVENTS")
-	detailHeader := HeaderStyle.Width(rightWidth).Align(lipgloss.Center).Render("EVENT DETAIL")
+	listTitle, detailTitle := "EVENTS", "EVENT DETAIL"
+	switch m.mode {
+	case modeCommands:
+		listTitle, detailTitle = "COMMANDS", "COMMAND DETAIL"
+	case modeTimeline:
+		listTitle, detailTitle = "TIMELINE", "DETAIL"
+	case modeCorrelation:
+		listTitle, detailTitle = "CORRELATIONS", "DETAIL"
+	}
+	if m.facetsOpen {
+		detailTitle = "SERVICES"
+	} else if m.treeFocus {
+		detailTitle = "PAYLOAD"
+	}
+
+	eventsHeader := HeaderStyle.Width(leftWidth).Align(lipgloss.Center).Render(listTitle)
+	detailHeader := HeaderStyle.Width(rightWidth).Align(lipgloss.Center).Render(detailTitle)
 
 	headers := lipgloss.JoinHorizontal(lipgloss.Top, eventsHeader, " ", detailHeader)
 
@@ -331,8 +563,25 @@ func (m Model) View() string {
 	panels := lipgloss.JoinHorizontal(lipgloss.Top, eventsBox, " ", detailBox)
 
 	// Stats and help
-	stats := fmt.Sprintf("Events: %d | Selected: %d/%d", len(m.Events), m.selectedIndex+1, len(m.Events))
-	help := HelpStyle.Render("j/k: navigate | Esc: back | q: quit")
+	stats := fmt.Sprintf("Events: %d | Commands: %d | Selected: %d/%d",
+		len(m.Events), len(m.Commands), m.selectedIndex+1, m.rowCount())
+	if hidden := m.hiddenCount(); hidden > 0 {
+		stats += fmt.Sprintf(" | Hidden services: %d", hidden)
+	}
+	stats += m.streamStatus()
+	helpText := "j/k: navigate | /: filter | s: services | p: explore payload | d: payload diff | b: pin diff base | Tab: switch view | Esc: back | q: quit"
+	if m.mode == modeCorrelation {
+		helpText = "j/k: navigate | Enter/h/l: collapse/expand | /: filter | s: services | p: explore payload | d: payload diff | Tab: switch view | Esc: back | q: quit"
+	}
+	if m.facetsOpen {
+		helpText = "j/k: choose service | Space: toggle | o: only this service | a: show all | s/Esc: close | q: quit"
+	} else if m.treeFocus {
+		helpText = "j/k: move | Enter/Space: toggle | h/l: collapse/expand | C/E: collapse/expand all | p/Esc: close | q: quit"
+	}
+	help := HelpStyle.Render(helpText)
+	if search := m.renderSearch(); search != "" {
+		help = lipgloss.NewStyle().MarginTop(1).MaxWidth(m.width).Render(search)
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left,
 		title,
@@ -342,3 +591,55 @@ func (m Model) View() string {
 		help,
 	)
 }
+
+// titleText names what was looked up, and for correlation and command
+// lookups how many aggregates it led to
+func (m Model) titleText() string {
+	text := fmt.Sprintf("Event Debugger - %s: %s", strings.TrimSuffix(m.Lookup.Label(), " ID"), m.aggregateID)
+	if m.Lookup == "" || m.Lookup == models.LookupAggregate {
+		return text
+	}
+
+	aggregates := make(map[string]bool)
+	for _, c := range m.allCommands {
+		aggregates[c.AggregateID] = true
+	}
+	for _, e := range m.allEvents {
+		aggregates[e.Metadata.AggregateID] = true
+	}
+	return fmt.Sprintf("%s (%d aggregates)", text, len(aggregates))
+}
+
+// environment returns the environment the data came from, for the title bar
+func (m Model) environment() *config.Environment {
+	if m.Environment == "" || m.Config == nil {
+		return nil
+	}
+	if env := m.Config.FindEnvironment(m.Environment); env != nil {
+		return env
+	}
+	return &config.Environment{Name: m.Environment}
+}
+
+// bannerHeight returns the number of lines taken by the failure banner
+func (m Model) bannerHeight() int {
+	if len(m.Failures) == 0 {
+		return 0
+	}
+	return 1
+}
+
+// renderBanner lists the service calls that failed, e.g. "payment-service
+// events: 503", so a partial outage is not mistaken for missing data
+func (m Model) renderBanner() string {
+	if len(m.Failures) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(m.Failures))
+	for _, f := range m.Failures {
+		parts = append(parts, f.Summary())
+	}
+
+	return BannerStyle.MaxWidth(m.width).Render("⚠ Partial results: " + strings.Join(parts, " | "))
+}
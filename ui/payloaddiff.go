@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"drill/diff"
+	"drill/models"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// previousWithAlias returns the latest event before the one at pos in
+// allEvents on the same aggregate with the same alias
+func (m Model) previousWithAlias(pos int) (models.Event, bool) {
+	evt := m.allEvents[pos]
+	var prev models.Event
+	found := false
+	for _, e := range m.allEvents[:pos] {
+		if e.Metadata.EventAlias == evt.Metadata.EventAlias && e.Metadata.AggregateID == evt.Metadata.AggregateID {
+			prev, found = e, true
+		}
+	}
+	return prev, found
+}
+
+// selectedEvent returns the event under the cursor in any view, if the row
+// is an event, pointing into Events
+func (m Model) selectedEvent() (*models.Event, bool) {
+	switch m.mode {
+	case modeCommands:
+		return nil, false
+	case modeTimeline:
+		if m.selectedIndex < len(m.timeline) && m.timeline[m.selectedIndex].Event != nil {
+			return m.timeline[m.selectedIndex].Event, true
+		}
+	case modeCorrelation:
+		if m.selectedIndex < len(m.treeRows) && m.treeRows[m.selectedIndex].event != nil {
+			return m.treeRows[m.selectedIndex].event, true
+		}
+	default:
+		if m.selectedIndex < len(m.Events) {
+			return &m.Events[m.selectedIndex], true
+		}
+	}
+	return nil, false
+}
+
+// selectedCommand returns the command under the cursor in any view, if the
+// row is a command
+func (m Model) selectedCommand() (*models.Command, bool) {
+	var cmd *models.Command
+	switch m.mode {
+	case modeCommands:
+		if m.selectedIndex < len(m.Commands) {
+			cmd = &m.Commands[m.selectedIndex]
+		}
+	case modeTimeline:
+		if m.selectedIndex < len(m.timeline) {
+			cmd = m.timeline[m.selectedIndex].Command
+		}
+	case modeCorrelation:
+		if m.selectedIndex < len(m.treeRows) {
+			cmd = m.treeRows[m.selectedIndex].command
+		}
+	}
+	return cmd, cmd != nil
+}
+
+// toggleDiffBase pins the selected event as the one payloads are diffed
+// against, or unpins it when it already is
+func (m *Model) toggleDiffBase() {
+	evt, ok := m.selectedEvent()
+	if !ok {
+		return
+	}
+	pos, ok := m.position(evt)
+	if !ok {
+		return
+	}
+	if m.diffBase != nil && m.diffBasePos == pos {
+		m.diffBase = nil
+	} else {
+		pinned := *evt
+		m.diffBase, m.diffBasePos = &pinned, pos
+		m.payloadDiff = true
+	}
+}
+
+// renderPayloadDiff compares evt's payload with the pinned event, or else
+// the previous event with the same alias. pos is evt's position in
+// allEvents.
+func (m Model) renderPayloadDiff(evt models.Event, pos int) string {
+	if !m.payloadDiff {
+		return ""
+	}
+
+	labelStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#888888"))
+	var sb strings.Builder
+	sb.WriteString("\n\n")
+
+	base, ok := m.previousWithAlias(pos)
+	against := "previous " + evt.Metadata.EventAlias
+	if m.diffBase != nil {
+		base, ok, against = *m.diffBase, true, "pinned "+m.diffBase.Metadata.EventAlias
+	}
+	if !ok {
+		sb.WriteString(labelStyle.Render("Payload diff:"))
+		sb.WriteString("\n")
+		sb.WriteString(HelpStyle.UnsetMarginTop().Render("(no earlier " + evt.Metadata.EventAlias + " to compare with, press b on one to pin it)"))
+		return sb.String()
+	}
+	if m.diffBase != nil && m.diffBasePos == pos {
+		sb.WriteString(labelStyle.Render("Payload diff:"))
+		sb.WriteString("\n")
+		sb.WriteString(HelpStyle.UnsetMarginTop().Render("(this is the pinned event, select another to compare)"))
+		return sb.String()
+	}
+
+	sb.WriteString(labelStyle.Render(fmt.Sprintf("Payload diff against %s (%s):",
+		against, base.Metadata.PersistedAt.Format("2006-01-02 15:04:05.000"))))
+	sb.WriteString("\n")
+	changes := diff.Payloads(base.Payload, evt.Payload)
+	if len(changes) == 0 {
+		sb.WriteString(HelpStyle.UnsetMarginTop().Render("(identical)"))
+		return sb.String()
+	}
+	sb.WriteString(renderChanges(changes))
+	return sb.String()
+}
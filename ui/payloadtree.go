@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// selectedPayload returns the payload of the selected row with a key
+// identifying the row, and false for rows without one such as correlation
+// group headers
+func (m Model) selectedPayload() (key, payload string, ok bool) {
+	if evt, ok := m.selectedEvent(); ok {
+		pos, _ := m.position(evt)
+		return fmt.Sprintf("event/%d", pos), evt.Payload, true
+	}
+
+	cmd, ok := m.selectedCommand()
+	if !ok {
+		return "", "", false
+	}
+	return "command/" + cmd.ServiceName + "/" + cmd.CommandID, cmd.Payload, true
+}
+
+// syncTree rebuilds the payload tree when the selected row has changed
+func (m *Model) syncTree() {
+	key, payload, ok := m.selectedPayload()
+	if !ok {
+		m.tree, m.treeKey, m.treeFocus = nil, "", false
+		return
+	}
+	if key != m.treeKey || m.tree == nil {
+		m.tree, m.treeKey = newJSONTree(payload), key
+	}
+}
+
+// payloadView renders the selected row's payload tree for the detail pane
+func (m Model) payloadView() string {
+	if m.tree == nil {
+		return ""
+	}
+	return m.tree.render(false)
+}
+
+// showTreeCursor fills the detail pane with the payload tree, scrolled to
+// keep the cursor in view
+func (m *Model) showTreeCursor() {
+	m.detailViewport.SetContent(m.tree.render(true))
+	switch {
+	case m.tree.cursor < m.detailViewport.YOffset:
+		m.detailViewport.SetYOffset(m.tree.cursor)
+	case m.tree.cursor >= m.detailViewport.YOffset+m.detailViewport.Height:
+		m.detailViewport.SetYOffset(m.tree.cursor - m.detailViewport.Height + 1)
+	}
+}
+
+// updatePayloadTree handles keys while exploring the payload tree
+func (m Model) updatePayloadTree(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc", "p":
+		m.treeFocus = false
+		m.updateDetailView()
+		return m, nil
+	case "up", "k":
+		m.tree.move(-1)
+	case "down", "j":
+		m.tree.move(1)
+	case "pgup":
+		m.tree.move(-10)
+	case "pgdown":
+		m.tree.move(10)
+	case "home", "g":
+		m.tree.cursor = 0
+	case "end", "G":
+		m.tree.move(len(m.tree.lines()))
+	case "enter", " ":
+		m.tree.toggle()
+	case "left", "h":
+		m.tree.setCollapsed(true)
+	case "right", "l":
+		m.tree.setCollapsed(false)
+	case "C":
+		m.tree.setAll(true)
+	case "E":
+		m.tree.setAll(false)
+	}
+
+	m.showTreeCursor()
+	return m, nil
+}
@@ -0,0 +1,221 @@
+package ui
+
+import (
+	"context"
+	"drill/fetcher"
+	"drill/mock"
+	"drill/models"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// progressStep tracks one service/endpoint call on the loading screen
+type progressStep struct {
+	ServiceName string
+	StepType    string
+	Target      string // aggregate being loaded after a related lookup, empty otherwise
+	Done        bool
+	Err         error
+	Items       int
+	Pages       int
+	Latency     time.Duration
+}
+
+func (m *EntryModel) initProgressSteps(isMock bool, id string) {
+	var services []models.ServiceConfig
+	if isMock {
+		services = mock.MockServices
+	} else {
+		services = fetcher.QueryableServices(m.services, m.lookup, id)
+	}
+
+	m.progressSteps = make([]progressStep, 0, len(services)*2)
+	for _, svc := range services {
+		m.progressSteps = append(m.progressSteps,
+			progressStep{ServiceName: svc.Name, StepType: "events"},
+			progressStep{ServiceName: svc.Name, StepType: "commands"},
+		)
+	}
+	m.currentStep = 0
+	m.progressPercent = 0
+	m.steps = make(chan FetchStepMsg, len(m.progressSteps))
+}
+
+// relatedSteps replaces the steps once a correlation or command lookup has
+// found its aggregates, with one step per aggregate, service and endpoint
+func (m *EntryModel) relatedSteps(aggregates []string) {
+	m.progressSteps = nil
+	for _, id := range aggregates {
+		for _, svc := range fetcher.QueryableServices(m.services, models.LookupAggregate, id) {
+			m.progressSteps = append(m.progressSteps,
+				progressStep{ServiceName: svc.Name, StepType: "events", Target: id},
+				progressStep{ServiceName: svc.Name, StepType: "commands", Target: id},
+			)
+		}
+	}
+	m.currentStep = 0
+	m.progressPercent = 0
+	m.loadingMsg = fmt.Sprintf("Found %d aggregates, loading their histories...", len(aggregates))
+}
+
+// applyStep records a page or a finished call on the matching step and
+// advances the bar
+func (m *EntryModel) applyStep(msg FetchStepMsg) {
+	if msg.Related != nil {
+		m.relatedSteps(msg.Related)
+		return
+	}
+
+	for i := range m.progressSteps {
+		step := &m.progressSteps[i]
+		if step.ServiceName != msg.ServiceName || step.StepType != msg.StepType || step.Done {
+			continue
+		}
+		if step.Target != "" && step.Target != msg.Target {
+			continue
+		}
+		step.Items = msg.Items
+		step.Pages = msg.Pages
+		if !msg.Done {
+			m.loadingMsg = fmt.Sprintf("Fetched page %d of %s from %s", msg.Pages, msg.StepType, msg.ServiceName)
+			return
+		}
+		step.Done = true
+		step.Err = msg.Err
+		step.Latency = msg.Latency
+		m.currentStep++
+		break
+	}
+
+	if msg.Err != nil {
+		m.loadingMsg = fmt.Sprintf("%s %s failed: %v", msg.ServiceName, msg.StepType, msg.Err)
+	} else {
+		m.loadingMsg = fmt.Sprintf("Fetched %s from %s", msg.StepType, msg.ServiceName)
+	}
+	if len(m.progressSteps) > 0 {
+		m.progressPercent = float64(m.currentStep) / float64(len(m.progressSteps))
+	}
+}
+
+// startFetch begins a new fetch generation and returns its context
+func (m *EntryModel) startFetch() context.Context {
+	m.fetchID++
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	return ctx
+}
+
+// waitForStep blocks until the fetch reports the next finished call,
+// stamping it with the fetch's generation. It returns nil once the channel
+// is closed, ending the chain.
+func waitForStep(steps <-chan FetchStepMsg, fetch int) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-steps
+		if !ok {
+			return nil
+		}
+		msg.Fetch = fetch
+		return msg
+	}
+}
+
+// sendStep reports a step unless the fetch was cancelled, so a fetch whose
+// channel is no longer read does not block
+func sendStep(ctx context.Context, steps chan<- FetchStepMsg, msg FetchStepMsg) {
+	select {
+	case steps <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// stepFromResult converts a finished call, or with done false the latest
+// page of one still in progress along with its rows, into a step message
+func stepFromResult(r fetcher.FetchResult, done bool) FetchStepMsg {
+	stepType := "events"
+	if r.Endpoint == fetcher.EndpointCommands {
+		stepType = "commands"
+	}
+	msg := FetchStepMsg{
+		ServiceName: r.Service,
+		StepType:    stepType,
+		Target:      r.ID,
+		Done:        done,
+		Err:         r.Error,
+		Items:       r.Items,
+		Pages:       r.Pages,
+		Latency:     r.Latency,
+	}
+	if !done {
+		msg.Events, msg.Commands = r.Events, r.Commands
+	}
+	return msg
+}
+
+// maxStepRows limits the loading screen's step list; beyond it finished
+// calls are hidden
+const maxStepRows = 12
+
+func (m EntryModel) renderSteps() string {
+	var sb strings.Builder
+
+	steps := m.progressSteps
+	if len(steps) > maxStepRows {
+		steps = nil
+		for _, step := range m.progressSteps {
+			if !step.Done || step.Err != nil {
+				steps = append(steps, step)
+			}
+		}
+	}
+	hidden := len(m.progressSteps) - len(steps)
+	if len(steps) > maxStepRows {
+		hidden += len(steps) - maxStepRows
+		steps = steps[:maxStepRows]
+	}
+
+	for _, step := range steps {
+		var status string
+		switch {
+		case !step.Done && step.Pages > 0:
+			status = HelpStyle.UnsetMarginTop().Render(fmt.Sprintf("⟳  %d items, %d pages so far", step.Items, step.Pages))
+		case !step.Done:
+			status = HelpStyle.UnsetMarginTop().Render("…  waiting")
+		case step.Err != nil:
+			status = FailedCommandStyle.Render(fmt.Sprintf("✗  %v (%s)", step.Err, step.Latency.Round(time.Millisecond)))
+		case step.Pages > 1:
+			status = SuccessCommandStyle.Render(fmt.Sprintf("✓  %d items in %d pages (%s)", step.Items, step.Pages, step.Latency.Round(time.Millisecond)))
+		default:
+			status = SuccessCommandStyle.Render(fmt.Sprintf("✓  %d items (%s)", step.Items, step.Latency.Round(time.Millisecond)))
+		}
+
+		label := CreateServiceStyle(step.ServiceName).Render(step.ServiceName) + " " + step.StepType
+		if step.Target != "" {
+			label += HelpStyle.UnsetMarginTop().Render(" " + shortID(step.Target))
+		}
+		name := lipgloss.NewStyle().Width(34).Render(label)
+		sb.WriteString(lipgloss.NewStyle().Width(80).Render(name + status))
+		sb.WriteString("\n")
+	}
+
+	if hidden > 0 {
+		sb.WriteString(HelpStyle.UnsetMarginTop().Render(fmt.Sprintf("… %d more calls", hidden)))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// shortID abbreviates a UUID to its first block for compact labels
+func shortID(id string) string {
+	if i := strings.Index(id, "-"); i > 0 {
+		return id[:i]
+	}
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
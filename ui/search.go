@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"drill/filter"
+	"drill/models"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// eventMatches reports whether an event's alias, service, IDs or payload
+// contain the lowercased query
+func eventMatches(evt models.Event, query string) bool {
+	return containsFold(query,
+		evt.Metadata.EventAlias,
+		evt.ServiceName,
+		evt.Metadata.CorrelationID,
+		evt.Metadata.EventID,
+		evt.Payload,
+	)
+}
+
+// commandMatches reports whether a command's alias, service, status, IDs or
+// payload contain the lowercased query
+func commandMatches(cmd models.Command, query string) bool {
+	return containsFold(query,
+		cmd.CommandAlias,
+		cmd.ServiceName,
+		string(cmd.CommandStatus),
+		cmd.CorrelationID,
+		cmd.CommandID,
+		cmd.Payload,
+	)
+}
+
+func containsFold(query string, fields ...string) bool {
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchMatchers returns the event and command predicates for the search:
+// a filter expression when the query contains an operator, otherwise a
+// free-text match. Both are nil when nothing should be filtered out.
+func (m *Model) searchMatchers() (func(models.Event) bool, func(models.Command) bool) {
+	m.filterErr = nil
+	query := strings.TrimSpace(m.filter)
+	if query == "" {
+		return nil, nil
+	}
+
+	if filter.LooksLikeExpression(query) {
+		f, err := filter.Parse(query)
+		if err != nil {
+			// keep every row visible while the expression is being typed
+			m.filterErr = err
+			return nil, nil
+		}
+		return f.MatchEvent, f.MatchCommand
+	}
+
+	query = strings.ToLower(query)
+	return func(e models.Event) bool { return eventMatches(e, query) },
+		func(c models.Command) bool { return commandMatches(c, query) }
+}
+
+// applyFilter narrows Events and Commands to the rows matching the search
+// from services that are not hidden, and rebuilds the derived views
+func (m *Model) applyFilter() {
+	matchEvent, matchCommand := m.searchMatchers()
+	if matchEvent == nil && len(m.hiddenServices) == 0 {
+		m.Events, m.Commands = m.allEvents, m.allCommands
+		m.eventPos = make([]int, len(m.allEvents))
+		for i := range m.eventPos {
+			m.eventPos[i] = i
+		}
+	} else {
+		m.Events, m.Commands, m.eventPos = nil, nil, nil
+		for i, e := range m.allEvents {
+			if !m.hiddenServices[e.ServiceName] && (matchEvent == nil || matchEvent(e)) {
+				m.Events = append(m.Events, e)
+				m.eventPos = append(m.eventPos, i)
+			}
+		}
+		for _, c := range m.allCommands {
+			if !m.hiddenServices[c.ServiceName] && (matchCommand == nil || matchCommand(c)) {
+				m.Commands = append(m.Commands, c)
+			}
+		}
+	}
+
+	m.timeline = buildTimeline(m.Events, m.Commands)
+	m.correlationGroups = buildCorrelationGroups(m.timeline)
+	m.treeRows = buildTreeRows(m.correlationGroups, m.collapsed)
+
+	if m.selectedIndex >= m.rowCount() {
+		m.selectedIndex = max(m.rowCount()-1, 0)
+	}
+}
+
+// startSearch opens the search prompt, editing the current filter
+func (m *Model) startSearch() tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "text, or an expression like service=orders AND payload.amount>50"
+	ti.SetValue(m.filter)
+	ti.CursorEnd()
+	m.searchInput = ti
+	m.searching = true
+	return m.searchInput.Focus()
+}
+
+// updateSearch handles keys while the search prompt is open. The search is
+// a filter: the list narrows to the matching rows as the query changes, so
+// moving through it with j/k visits only matches. Enter keeps the filter,
+// Esc clears it.
+func (m Model) updateSearch(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "enter":
+		m.searching = false
+		m.searchInput.Blur()
+		return m, nil
+	case "esc":
+		m.searching = false
+		m.searchInput.Blur()
+		m.filter = ""
+	default:
+		var cmd tea.Cmd
+		m.searchInput, cmd = m.searchInput.Update(msg)
+		if m.searchInput.Value() == m.filter {
+			return m, cmd
+		}
+		m.filter = m.searchInput.Value()
+		m.selectedIndex = 0
+		m.applyFilter()
+		m.updateDetailView()
+		m.updateListView()
+		return m, cmd
+	}
+
+	m.applyFilter()
+	m.updateDetailView()
+	m.updateListView()
+	return m, nil
+}
+
+// matchCount returns how many events and commands match the filter
+func (m Model) matchCount() int {
+	return len(m.Events) + len(m.Commands)
+}
+
+// renderSearch returns the footer line for the search prompt or the active
+// filter, or "" when there is neither
+func (m Model) renderSearch() string {
+	if m.searching && m.filterErr != nil {
+		return m.searchInput.View() + FailedCommandStyle.Render("  "+m.filterErr.Error())
+	}
+	if m.searching {
+		return m.searchInput.View() + HelpStyle.UnsetMarginTop().Render(fmt.Sprintf("  %d matches | Enter: keep | Esc: clear", m.matchCount()))
+	}
+	if m.filterErr != nil {
+		return FailedCommandStyle.Render(m.filterErr.Error()) + HelpStyle.UnsetMarginTop().Render(" | /: edit | Esc: clear")
+	}
+	if m.filter != "" {
+		return HelpStyle.UnsetMarginTop().Render(fmt.Sprintf("Filter %q: showing %d of %d rows | /: edit | Esc: clear",
+			m.filter, m.matchCount(), len(m.allEvents)+len(m.allCommands)))
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"drill/diff"
+	"drill/models"
+	"drill/projection"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// newReplay prepares state reconstruction for the loaded events, with the
+// reducers from the configuration
+func (m Model) newReplay() (*projection.Replay, error) {
+	var reducers map[string]models.ReducerConfig
+	if m.Config != nil {
+		reducers = m.Config.Reducers
+	}
+	p, err := projection.New(reducers)
+	if err != nil {
+		return nil, err
+	}
+	return p.Replay(m.allEvents), nil
+}
+
+// eventDetail renders an event in Events followed by its payload diff, when
+// turned on, and its aggregate's reconstructed state
+func (m Model) eventDetail(evt *models.Event) string {
+	pos, ok := m.position(evt)
+	if !ok {
+		return formatEventDetail(*evt, m.payloadView())
+	}
+	return formatEventDetail(*evt, m.payloadView()) + m.renderPayloadDiff(*evt, pos) + m.renderState(pos)
+}
+
+// position returns where evt, a row of Events, sits in allEvents. Rows are
+// told apart by address, since event IDs can repeat.
+func (m Model) position(evt *models.Event) (int, bool) {
+	for i := range m.Events {
+		if &m.Events[i] == evt {
+			return m.eventPos[i], true
+		}
+	}
+	return 0, false
+}
+
+// renderState shows the aggregate's state after the event at pos in
+// allEvents, with the keys it added or changed highlighted and those it
+// removed listed below
+func (m Model) renderState(pos int) string {
+	labelStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#888888"))
+
+	if m.replayErr != nil {
+		return "\n\n" + labelStyle.Render("State:") + "\n" + FailedCommandStyle.Render(m.replayErr.Error())
+	}
+	if m.replay == nil {
+		return ""
+	}
+	step, ok := m.replay.At(pos)
+	if !ok {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n")
+	sb.WriteString(labelStyle.Render(fmt.Sprintf("State after event %d of %d:", step.Index+1, step.Count)))
+	sb.WriteString("\n")
+
+	changes := step.Changes()
+	marks := make(map[string]diff.Kind, len(changes))
+	for _, c := range changes {
+		marks[c.Path] = c.Kind
+	}
+	writeState(&sb, step.After, "", "", "", 0, marks, lipgloss.NewStyle().Foreground(lipgloss.Color("#ffffff")))
+
+	var removed []diff.Change
+	for _, c := range changes {
+		if c.Kind == diff.Removed {
+			removed = append(removed, c)
+		}
+	}
+	if len(removed) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(renderChanges(removed))
+	}
+	if len(changes) == 0 && step.Index > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(HelpStyle.UnsetMarginTop().Render("(unchanged by this event)"))
+	}
+
+	return sb.String()
+}
+
+// writeState writes v as indented JSON, one line per scalar or bracket, in
+// the style for its path when this event added or changed it. Nested
+// values inherit the style of a marked parent.
+func writeState(sb *strings.Builder, v interface{}, path, prefix, suffix string, depth int, marks map[string]diff.Kind, style lipgloss.Style) {
+	switch marks[path] {
+	case diff.Added:
+		style = DiffAddedStyle
+	case diff.Changed:
+		style = DiffChangedStyle
+	}
+	indent := strings.Repeat("  ", depth)
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			sb.WriteString(style.Render(indent+prefix+"{}"+suffix) + "\n")
+			return
+		}
+		sb.WriteString(style.Render(indent+prefix+"{") + "\n")
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			writeState(sb, val[k], child, fmt.Sprintf("%q: ", k), separator(i, len(keys)), depth+1, marks, style)
+		}
+		sb.WriteString(style.Render(indent+"}"+suffix) + "\n")
+	case []interface{}:
+		if len(val) == 0 {
+			sb.WriteString(style.Render(indent+prefix+"[]"+suffix) + "\n")
+			return
+		}
+		sb.WriteString(style.Render(indent+prefix+"[") + "\n")
+		for i, item := range val {
+			writeState(sb, item, fmt.Sprintf("%s[%d]", path, i), "", separator(i, len(val)), depth+1, marks, style)
+		}
+		sb.WriteString(style.Render(indent+"]"+suffix) + "\n")
+	default:
+		sb.WriteString(style.Render(indent+prefix+diff.Format(val)+suffix) + "\n")
+	}
+}
+
+// separator returns the comma that follows item i of n
+func separator(i, n int) string {
+	if i < n-1 {
+		return ","
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"drill/models"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateStream handles the messages of a fetch that is still running after
+// the data view opened on its first rows. Each page's rows are added as
+// they arrive; the finished fetch's report then replaces them, so the view
+// ends with what a fetch that was not streamed would show.
+func (m Model) updateStream(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case FetchStepMsg:
+		if m.loader == nil || msg.Fetch != m.loader.fetchID {
+			return m, nil
+		}
+		m.loader.applyStep(msg)
+		m.appendPage(msg)
+		return m, waitForStep(m.loader.steps, m.loader.fetchID)
+
+	case LoadCompleteMsg:
+		if m.loader == nil || msg.Fetch != m.loader.fetchID {
+			return m, nil
+		}
+		m.loader.completeFetch(msg)
+		m.loader, m.streamed = nil, nil
+		m.Failures = msg.Failures
+		m.replaceData(msg.Events, msg.Commands)
+		// The failure banner may have changed the panel heights
+		return m, m.resize()
+
+	case LoadErrorMsg:
+		if m.loader == nil || msg.Fetch != m.loader.fetchID {
+			return m, nil
+		}
+		entry := *m.loader
+		entry.loading = false
+		entry.cancel = nil
+		entry.err = msg.Err
+		return entry, m.resize()
+	}
+	return m, nil
+}
+
+// cancelStream stops a fetch that is still streaming in and returns to the
+// entry screen, as Esc does on the loading screen
+func (m Model) cancelStream() (tea.Model, tea.Cmd) {
+	entry := *m.loader
+	entry.cancelFetch()
+	return entry, m.resize()
+}
+
+// resize resends the window size, for the next model or for a layout
+// change
+func (m Model) resize() tea.Cmd {
+	return func() tea.Msg {
+		return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+	}
+}
+
+// appendPage adds the rows of a streamed page. Correlation and command
+// lookups fetch the rows their first calls found again with the histories
+// of their aggregates, so those are skipped as FetchRelated skips them.
+func (m *Model) appendPage(msg FetchStepMsg) {
+	// Rows are only split into allEvents and a filtered Events once the
+	// view has been sized
+	events, commands := m.allEvents, m.allCommands
+	if !m.ready {
+		events, commands = m.Events, m.Commands
+	}
+
+	related := m.Lookup != "" && m.Lookup != models.LookupAggregate
+	if related && m.streamed == nil {
+		m.streamed = make(map[string]bool)
+	}
+	for _, e := range msg.Events {
+		key := "event/" + e.ServiceName + "/" + e.Metadata.EventID
+		if related && e.Metadata.EventID != "" {
+			if m.streamed[key] {
+				continue
+			}
+			m.streamed[key] = true
+		}
+		events = append(events, e)
+	}
+	for _, c := range msg.Commands {
+		key := "command/" + c.ServiceName + "/" + c.CommandID
+		if related && c.CommandID != "" {
+			if m.streamed[key] {
+				continue
+			}
+			m.streamed[key] = true
+		}
+		commands = append(commands, c)
+	}
+
+	m.replaceData(events, commands)
+}
+
+// replaceData swaps in a new set of rows, keeping the search, hidden
+// services, selected row and pinned diff base
+func (m *Model) replaceData(events []models.Event, commands []models.Command) {
+	if !m.ready {
+		// prepared once the view is sized
+		m.Events, m.Commands = events, commands
+		return
+	}
+
+	row := m.selectedRow()
+	offset := m.detailViewport.YOffset
+	m.Events, m.Commands = events, commands
+	m.prepareData()
+	if m.diffBase != nil {
+		m.diffBase = m.findPinned()
+	}
+
+	// Rows arriving earlier in time push the selected row down. Follow it,
+	// keeping its payload tree and the detail pane scrolled where it was.
+	if row != nil && row != m.selectedRow() {
+		m.selectRow(row)
+	}
+	same := row != nil && row == m.selectedRow()
+	if key, _, ok := m.selectedPayload(); same && ok {
+		m.treeKey = key
+	} else if !same {
+		m.treeKey = ""
+	}
+	m.updateListView()
+	m.updateDetailView()
+	if same && !m.treeFocus {
+		m.detailViewport.SetYOffset(offset)
+	}
+}
+
+// selectRow moves the selection to row, if it is still listed
+func (m *Model) selectRow(row interface{}) {
+	at := m.selectedIndex
+	for m.selectedIndex = 0; m.selectedIndex < m.rowCount(); m.selectedIndex++ {
+		if m.selectedRow() == row {
+			return
+		}
+	}
+	m.selectedIndex = at
+}
+
+// selectedRow returns a copy of the selected event or command, or nil for
+// other rows such as correlation group headers
+func (m Model) selectedRow() interface{} {
+	if evt, ok := m.selectedEvent(); ok {
+		return *evt
+	}
+	if cmd, ok := m.selectedCommand(); ok {
+		return *cmd
+	}
+	return nil
+}
+
+// findPinned locates the pinned diff base among the new rows, updating its
+// position, or returns nil when it is no longer there
+func (m *Model) findPinned() *models.Event {
+	for i, e := range m.allEvents {
+		if e == *m.diffBase {
+			m.diffBasePos = i
+			return m.diffBase
+		}
+	}
+	return nil
+}
+
+// streamStatus reports the progress of a fetch still streaming in, for the
+// stats line, or "" once it has finished
+func (m Model) streamStatus() string {
+	if m.loader == nil {
+		return ""
+	}
+	return fmt.Sprintf(" | Loading: %d of %d calls complete, Esc to cancel", m.loader.currentStep, len(m.loader.progressSteps))
+}
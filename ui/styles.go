@@ -61,6 +61,59 @@ var (
 	SuccessCommandStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("#69f0ae"))
 
+	CommandTagStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ffb74d")).
+			Bold(true)
+
+	EventTagStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#4fc3f7")).
+			Bold(true)
+
+	BannerStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ff5252")).
+			Bold(true)
+
+	EnvironmentStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("#ffffff")).
+				Background(lipgloss.Color("#00897b")).
+				Padding(0, 2)
+
+	ProductionStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#ffffff")).
+			Background(lipgloss.Color("#d32f2f")).
+			Padding(0, 2)
+
+	// Diff styles, for fields and entries added, removed or changed
+	DiffAddedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#69f0ae"))
+
+	DiffRemovedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#ff5252"))
+
+	DiffChangedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#ffb74d"))
+
+	// JSON tree styles, by value type
+	JSONKeyStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#82b1ff"))
+
+	JSONStringStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#a5d6a7"))
+
+	JSONNumberStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ffcc80"))
+
+	JSONBoolStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ce93d8"))
+
+	JSONNullStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#888888"))
+
+	JSONPunctStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ffffff"))
+
 	BorderStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(lipgloss.Color("#5c6bc0"))
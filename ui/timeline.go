@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"drill/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// timelineEntry is a single row in the merged timeline; exactly one of
+// Command or Event is set
+type timelineEntry struct {
+	Command *models.Command
+	Event   *models.Event
+}
+
+func (e timelineEntry) persistedAt() time.Time {
+	if e.Command != nil {
+		return e.Command.PersistedAt
+	}
+	return e.Event.Metadata.PersistedAt
+}
+
+func (e timelineEntry) serviceName() string {
+	if e.Command != nil {
+		return e.Command.ServiceName
+	}
+	return e.Event.ServiceName
+}
+
+func (e timelineEntry) alias() string {
+	if e.Command != nil {
+		return e.Command.CommandAlias
+	}
+	return e.Event.Metadata.EventAlias
+}
+
+func (e timelineEntry) correlationID() string {
+	if e.Command != nil {
+		return e.Command.CorrelationID
+	}
+	return e.Event.Metadata.CorrelationID
+}
+
+func (e timelineEntry) failed() bool {
+	return e.Command != nil && e.Command.CommandStatus == models.CommandFailed
+}
+
+// buildTimeline interleaves commands and events by persistedAt. Commands
+// sort ahead of events persisted at the same instant, since they cause them.
+func buildTimeline(events []models.Event, commands []models.Command) []timelineEntry {
+	entries := make([]timelineEntry, 0, len(events)+len(commands))
+	for i := range commands {
+		entries = append(entries, timelineEntry{Command: &commands[i]})
+	}
+	for i := range events {
+		entries = append(entries, timelineEntry{Event: &events[i]})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].persistedAt().Before(entries[j].persistedAt())
+	})
+
+	return entries
+}
+
+func (m Model) renderTimeline() string {
+	if len(m.timeline) == 0 {
+		return "No commands or events found"
+	}
+
+	const (
+		timeWidth    = 22
+		typeWidth    = 5
+		serviceWidth = 20
+		aliasWidth   = 22
+		corrWidth    = 38
+		cellPadding  = "  " // spacing between cells
+	)
+
+	var sb strings.Builder
+
+	// Header row
+	timeCol := lipgloss.NewStyle().Width(timeWidth).Render("Time")
+	typeCol := lipgloss.NewStyle().Width(typeWidth).Render("Type")
+	svcCol := lipgloss.NewStyle().Width(serviceWidth).Render("Service")
+	aliasCol := lipgloss.NewStyle().Width(aliasWidth).Render("Name")
+	corrCol := lipgloss.NewStyle().Width(corrWidth).Render("CorrelationID")
+
+	header := lipgloss.JoinHorizontal(lipgloss.Left, timeCol, cellPadding, typeCol, cellPadding, svcCol, cellPadding, aliasCol, cellPadding, corrCol)
+	sb.WriteString(TableHeaderStyle.Render(header))
+	sb.WriteString("\n")
+
+	for i, entry := range m.timeline {
+		timeStr := entry.persistedAt().Format("2006-01-02 15:04:05")
+		timeCell := lipgloss.NewStyle().Width(timeWidth).Render(timeStr)
+
+		// CMD/EVT marker, red for failed commands
+		var typeText string
+		switch {
+		case entry.failed():
+			typeText = FailedCommandStyle.Bold(true).Render("CMD")
+		case entry.Command != nil:
+			typeText = CommandTagStyle.Render("CMD")
+		default:
+			typeText = EventTagStyle.Render("EVT")
+		}
+		typeCell := lipgloss.NewStyle().Width(typeWidth).Render(typeText)
+
+		svcText := CreateServiceStyle(entry.serviceName()).Render(entry.serviceName())
+		svcCell := lipgloss.NewStyle().Width(serviceWidth).Render(svcText)
+
+		alias := entry.alias()
+		if len(alias) > aliasWidth-2 {
+			alias = alias[:aliasWidth-5] + "..."
+		}
+		if entry.failed() {
+			alias = FailedCommandStyle.Render(alias)
+		}
+		aliasCell := lipgloss.NewStyle().Width(aliasWidth).Render(alias)
+
+		correlationText := CreateCorrelationStyle(entry.correlationID()).Render(entry.correlationID())
+		corrCell := lipgloss.NewStyle().Width(corrWidth).Render(correlationText)
+
+		row := lipgloss.JoinHorizontal(lipgloss.Left, timeCell, cellPadding, typeCell, cellPadding, svcCell, cellPadding, aliasCell, cellPadding, corrCell)
+
+		if i == m.selectedIndex {
+			row = SelectedRowStyle.Render(row)
+		}
+
+		sb.WriteString(row)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func (m Model) renderTimelineDetail() string {
+	if len(m.timeline) == 0 || m.selectedIndex >= len(m.timeline) {
+		return "Nothing selected"
+	}
+
+	entry := m.timeline[m.selectedIndex]
+	if entry.Command != nil {
+		return formatCommandDetail(*entry.Command, m.payloadView())
+	}
+	return m.eventDetail(entry.Event)
+}